@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/schema"
+)
+
+const (
+	default_max_body_bytes = 1 << 20
+)
+
+type bodyRouteCfg struct {
+	MaxBytes   int64  `json:"max_bytes"`
+	SchemaFile string `json:"schema_file"`
+	schema     *schema.Schema
+}
+
+//no schema file - no validation
+func (b *bodyRouteCfg) loadSchema() error {
+	if len(b.SchemaFile) == 0 {
+		return nil
+	}
+	s, err := schema.Load(b.SchemaFile)
+	if err != nil {
+		return err
+	}
+	b.schema = s
+	return nil
+}
+
+type requestBodyCfg struct {
+	MaxBytes int64        `json:"max_bytes"`
+	Create   bodyRouteCfg `json:"create"`
+	Update   bodyRouteCfg `json:"update"`
+	Patch    bodyRouteCfg `json:"patch"`
+}
+
+//limit of a route overrides common one, there is always a limit
+func (b *requestBodyCfg) maxBytes(route *bodyRouteCfg) int64 {
+	if route.MaxBytes > 0 {
+		return route.MaxBytes
+	}
+	if b.MaxBytes > 0 {
+		return b.MaxBytes
+	}
+	return default_max_body_bytes
+}
+
+func (b *requestBodyCfg) loadSchemas() error {
+	for _, route := range []*bodyRouteCfg{&b.Create, &b.Update, &b.Patch} {
+		err := route.loadSchema()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//newBodyExtractor checks content type and size of body first, so invalid requests are not read in full
+func (b *requestBodyCfg) newBodyExtractor(route *bodyRouteCfg) logic.RequestDataExtractor {
+	getData := logic.NewJsonBodyExtractor(logic.NewBodyLimitExtractor(b.maxBytes(route), logic.GetRequestBodyData))
+	if route.schema == nil {
+		return getData
+	}
+	return logic.NewSchemaExtractor(route.schema, getData)
+}
@@ -2,36 +2,200 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis"
+
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/utils"
+)
+
+const (
+	log_format_text = "text"
+	log_format_json = "json"
+
+	default_app_timeout_seconds = 120
+	default_redis_address       = "localhost:6379"
+	default_bind_port           = 80
+
+	default_retry_base_backoff = 100 * time.Millisecond
+	default_retry_max_backoff  = 2 * time.Second
 )
 
 type redisCfg struct {
-	Address string `json:"address"`
-	DB      int    `json:"db"`
+	Address      string `json:"address"`
+	DB           int    `json:"db"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"password_file"`
+}
+
+type retryCfg struct {
+	MaxAttempts          int      `json:"max_attempts"`
+	BaseBackoffMs        int      `json:"base_backoff_ms"`
+	MaxBackoffMs         int      `json:"max_backoff_ms"`
+	Jitter               float64  `json:"jitter"`
+	RetryableStatusCodes []int    `json:"retryable_status_codes"`
+	Methods              []string `json:"methods"`
+}
+
+type breakerCfg struct {
+	FailureRatio     float64 `json:"failure_ratio"`
+	MinRequests      int     `json:"min_requests"`
+	WindowSeconds    int     `json:"window_seconds"`
+	CoolDownSeconds  int     `json:"cool_down_seconds"`
+	HalfOpenRequests int     `json:"half_open_requests"`
+}
+
+type endpointCfg struct {
+	Method string `json:"method"`
+	Url    string `json:"url"`
+}
+
+type endpointsCfg struct {
+	Get    endpointCfg `json:"get"`
+	Create endpointCfg `json:"create"`
+	Update endpointCfg `json:"update"`
+	Patch  endpointCfg `json:"patch"`
+	Delete endpointCfg `json:"delete"`
+}
+
+//fields, that are not set, are taken from default
+func (e *endpointCfg) toEndpoint(def sources.Endpoint) sources.Endpoint {
+	if len(e.Method) > 0 {
+		def.Method = e.Method
+	}
+	if len(e.Url) > 0 {
+		def.Url = e.Url
+	}
+	return def
+}
+
+type keyCfg struct {
+	Paths     []string `json:"paths"`
+	Separator string   `json:"separator"`
+}
+
+type logCfg struct {
+	Format string `json:"format"`
+	Level  string `json:"level"`
+}
+
+type tlsCfg struct {
+	CertFile          string `json:"cert_file"`
+	KeyFile           string `json:"key_file"`
+	MinVersion        string `json:"min_version"`
+	ClientCaFile      string `json:"client_ca_file"`
+	RequireClientCert bool   `json:"require_client_cert"`
+	CertCheckSeconds  int    `json:"cert_check_seconds"`
+	RedirectPort      int    `json:"redirect_port"`
 }
 
 type bindCfg struct {
 	Ip   string `json:"ip"`
 	Port int    `json:"port"`
+	Tls  tlsCfg `json:"tls"`
+}
+
+//TLS is terminated by service, when certificate is configured
+func (b *bindCfg) IsTls() bool {
+	return len(b.Tls.CertFile) > 0 || len(b.Tls.KeyFile) > 0
+}
+
+func (b *bindCfg) GetTlsOptions() (utils.TlsOptions, error) {
+	if len(b.Tls.CertFile) == 0 || len(b.Tls.KeyFile) == 0 {
+		return utils.TlsOptions{}, errors.New("both cert_file and key_file are required")
+	}
+	minVersion, err := utils.ParseTlsVersion(b.Tls.MinVersion)
+	if err != nil {
+		return utils.TlsOptions{}, err
+	}
+	opts := utils.TlsOptions{
+		CertFile:          b.Tls.CertFile,
+		KeyFile:           b.Tls.KeyFile,
+		MinVersion:        minVersion,
+		ClientCaFile:      b.Tls.ClientCaFile,
+		RequireClientCert: b.Tls.RequireClientCert,
+		CertCheckInterval: time.Duration(b.Tls.CertCheckSeconds) * time.Second,
+	}
+	if b.Tls.RedirectPort > 0 {
+		opts.RedirectAddress = fmt.Sprintf("%s:%v", b.Ip, b.Tls.RedirectPort)
+	}
+	return opts, nil
+}
+
+func (b *bindCfg) validate() []error {
+	errs := []error{}
+	if b.Port < 1 || b.Port > 65535 {
+		errs = append(errs, fmt.Errorf("bind.port %v is out of range", b.Port))
+	}
+	if !b.IsTls() {
+		if b.Tls.RedirectPort != 0 || len(b.Tls.ClientCaFile) > 0 {
+			errs = append(errs, errors.New("bind.tls requires cert_file and key_file"))
+		}
+		return errs
+	}
+	_, err := b.GetTlsOptions()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("bind.tls: %v", err))
+	}
+	if b.Tls.RedirectPort < 0 || b.Tls.RedirectPort > 65535 || b.Tls.RedirectPort == b.Port {
+		errs = append(errs, fmt.Errorf("bind.tls.redirect_port %v is out of range or is the same as bind.port", b.Tls.RedirectPort))
+	}
+	return errs
+}
+
+//adminCfg is a listener of ops endpoints (health, readiness, metrics, profiling), zero port disables it
+type adminCfg struct {
+	Ip   string `json:"ip"`
+	Port int    `json:"port"`
+}
+
+func (a *adminCfg) IsEnabled() bool {
+	return a.Port > 0
+}
+
+func (a *adminCfg) GetBind() string {
+	return fmt.Sprintf("%s:%v", a.Ip, a.Port)
 }
 
 type appCfg struct {
-	redisPassword     string   `json:"-"`
-	Api               string   `json:"api_url"`
-	CacheTtlSeconds   int      `json:"cache_ttl_seconds"`
-	AppTimeoutSeconds int      `json:"app_timeout_seconds"`
-	Redis             redisCfg `json:"redis"`
-	Bind              bindCfg  `json:"bind"`
+	//path of config file, empty if file wasn't loaded
+	path                        string
+	Api                         string         `json:"api_url"`
+	Endpoints                   endpointsCfg   `json:"endpoints"`
+	Key                         keyCfg         `json:"key"`
+	CacheTtlSeconds             int            `json:"cache_ttl_seconds"`
+	StaleWhileRevalidateSeconds int            `json:"stale_while_revalidate_seconds"`
+	StaleIfErrorSeconds         int            `json:"stale_if_error_seconds"`
+	CacheHeaders                []string       `json:"cache_headers"`
+	CachePartitionHeaders       []string       `json:"cache_partition_headers"`
+	AppTimeoutSeconds           int            `json:"app_timeout_seconds"`
+	WatchConfigSeconds          int            `json:"watch_config_seconds"`
+	Log                         logCfg         `json:"log"`
+	Upstream                    upstreamCfg    `json:"upstream"`
+	Readiness                   readinessCfg   `json:"readiness"`
+	RequestBody                 requestBodyCfg `json:"request_body"`
+	Webhook                     webhookCfg     `json:"webhook"`
+	Resources                   []resourceCfg  `json:"resources"`
+	Retry                       retryCfg       `json:"retry"`
+	CircuitBreaker              breakerCfg     `json:"circuit_breaker"`
+	Redis                       redisCfg       `json:"redis"`
+	Bind                        bindCfg        `json:"bind"`
+	Admin                       adminCfg       `json:"admin"`
 }
 
 func (a *appCfg) GetRedisOptions() *redis.Options {
 	return &redis.Options{
 		Addr:     a.Redis.Address,
-		Password: a.redisPassword,
+		Password: a.Redis.Password,
 		DB:       a.Redis.DB,
 	}
 }
@@ -40,22 +204,218 @@ func (a *appCfg) GetBind() string {
 	return fmt.Sprintf("%s:%v", a.Bind.Ip, a.Bind.Port)
 }
 
+//by default all levels are logged
+func (a *appCfg) GetLogLevel() (logs.Level, error) {
+	return logs.ParseLevel(a.Log.Level)
+}
+
+//by default logs are written as text. Level is not applied here, it's taken from switch, so it can be reloaded.
+func (a *appCfg) NewLogger(level *logs.LevelSwitch) (logs.Logger, error) {
+	switch a.Log.Format {
+	case log_format_text, "":
+		return logs.NewSwitchedLogger(logs.NewStdLogger(), level), nil
+	case log_format_json:
+		return logs.NewSwitchedLogger(logs.NewJsonLogger(os.Stderr, logs.LevelDebug), level), nil
+	}
+	return nil, fmt.Errorf("unknown log format '%v'", a.Log.Format)
+}
+
 func (a *appCfg) GetAppTimeout() time.Duration {
 	return time.Duration(a.AppTimeoutSeconds) * time.Second
 }
 
-func (a *appCfg) GetCacheTtl() time.Duration {
-	return time.Duration(a.CacheTtlSeconds) * time.Second
+//zero disables watching of config file, it's reloaded only on SIGHUP
+func (a *appCfg) GetWatchConfigInterval() time.Duration {
+	return time.Duration(a.WatchConfigSeconds) * time.Second
 }
 
-func getConfig(filename string, redisPassword string) (*appCfg, error) {
-	cfgData, err := ioutil.ReadFile(filename)
+//legacyResource is built from top-level fields, when resources are not configured. Its cache keys are not prefixed,
+//so values, cached before resources were introduced, are still used.
+func (a *appCfg) legacyResource() resourceCfg {
+	return resourceCfg{
+		Name:                        default_resource_name,
+		Path:                        default_resource_path,
+		Api:                         a.Api,
+		Endpoints:                   a.Endpoints,
+		Key:                         a.Key,
+		CacheTtlSeconds:             a.CacheTtlSeconds,
+		StaleWhileRevalidateSeconds: a.StaleWhileRevalidateSeconds,
+		StaleIfErrorSeconds:         a.StaleIfErrorSeconds,
+		CacheHeaders:                a.CacheHeaders,
+		CachePartitionHeaders:       a.CachePartitionHeaders,
+		RequestBody:                 a.RequestBody,
+		Webhook:                     a.Webhook,
+		legacy:                      true,
+	}
+}
+
+func (a *appCfg) GetReadinessTimeout() time.Duration {
+	return msOrDefault(a.Readiness.TimeoutMs, default_readiness_timeout)
+}
+
+func (a *appCfg) GetDrainDelay() time.Duration {
+	return time.Duration(a.Readiness.DrainSeconds) * time.Second
+}
+
+//by default only idempotent GET requests are retried, POST/PUT should be added to methods explicitly
+func (a *appCfg) GetRetryPolicy() sources.RetryPolicy {
+	policy := sources.RetryPolicy{
+		MaxAttempts:       a.Retry.MaxAttempts,
+		BaseBackoff:       msOrDefault(a.Retry.BaseBackoffMs, default_retry_base_backoff),
+		MaxBackoff:        msOrDefault(a.Retry.MaxBackoffMs, default_retry_max_backoff),
+		Jitter:            a.Retry.Jitter,
+		RetryableStatuses: a.Retry.RetryableStatusCodes,
+		Methods:           a.Retry.Methods,
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Methods == nil {
+		policy.Methods = []string{http.MethodGet}
+	}
+	if policy.RetryableStatuses == nil {
+		policy.RetryableStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	return policy
+}
+
+func (a *appCfg) GetBreakerPolicy() sources.BreakerPolicy {
+	return sources.BreakerPolicy{
+		FailureRatio:     a.CircuitBreaker.FailureRatio,
+		MinRequests:      a.CircuitBreaker.MinRequests,
+		Window:           time.Duration(a.CircuitBreaker.WindowSeconds) * time.Second,
+		CoolDown:         time.Duration(a.CircuitBreaker.CoolDownSeconds) * time.Second,
+		HalfOpenRequests: a.CircuitBreaker.HalfOpenRequests,
+	}
+}
+
+//defaultConfig is a base, that is overridden by config file, environment and command line
+func defaultConfig() *appCfg {
+	return &appCfg{
+		AppTimeoutSeconds: default_app_timeout_seconds,
+		Readiness: readinessCfg{
+			DrainSeconds: default_drain_seconds,
+		},
+		Redis: redisCfg{
+			Address: default_redis_address,
+		},
+		Bind: bindCfg{
+			Port: default_bind_port,
+		},
+	}
+}
+
+func (a *appCfg) secrets() []secret {
+	secrets := []secret{
+		{name: "redis password", value: &a.Redis.Password, file: &a.Redis.PasswordFile},
+		{name: "upstream API key", value: &a.Upstream.ApiKey, file: &a.Upstream.ApiKeyFile},
+		{name: "webhook secret", value: &a.Webhook.Secret, file: &a.Webhook.SecretFile},
+	}
+	for i := range a.Resources {
+		w := &a.Resources[i].Webhook
+		secrets = append(secrets, secret{name: fmt.Sprintf("webhook secret of resource '%v'", a.Resources[i].Name), value: &w.Secret, file: &w.SecretFile})
+	}
+	return secrets
+}
+
+//loadSecrets is called after every source of config, so value from later source overrides secret file of earlier one
+func (a *appCfg) loadSecrets() []error {
+	errs := []error{}
+	for _, s := range a.secrets() {
+		err := s.load()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+//validate returns all problems of config, so they can be fixed at once
+func (a *appCfg) validate() []error {
+	errs := []error{}
+	if len(a.Redis.Address) == 0 {
+		errs = append(errs, errors.New("redis.address is required"))
+	}
+	errs = append(errs, a.Bind.validate()...)
+	if a.Admin.Port < 0 || a.Admin.Port > 65535 {
+		errs = append(errs, fmt.Errorf("admin.port %v is out of range", a.Admin.Port))
+	}
+	if a.Admin.IsEnabled() && (a.Admin.Port == a.Bind.Port || a.Admin.Port == a.Bind.Tls.RedirectPort) {
+		errs = append(errs, fmt.Errorf("admin.port %v is already used by bind", a.Admin.Port))
+	}
+	if a.AppTimeoutSeconds < 1 {
+		errs = append(errs, errors.New("app_timeout_seconds must be positive"))
+	}
+	//shutdown is forced after app timeout, drain must end earlier
+	if a.Readiness.DrainSeconds < 0 || a.Readiness.DrainSeconds >= a.AppTimeoutSeconds {
+		errs = append(errs, fmt.Errorf("readiness.drain_seconds %v must be within [0, app_timeout_seconds)", a.Readiness.DrainSeconds))
+	}
+	if a.WatchConfigSeconds < 0 {
+		errs = append(errs, errors.New("watch_config_seconds must not be negative"))
+	}
+	level, err := a.GetLogLevel()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid log config: %v", err))
+	}
+	_, err = a.NewLogger(logs.NewLevelSwitch(level))
 	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid log config: %v", err))
+	}
+	_, err = a.Upstream.getProxy()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("upstream: %v", err))
+	}
+	retry := a.GetRetryPolicy()
+	if retry.MaxBackoff < retry.BaseBackoff {
+		errs = append(errs, fmt.Errorf("retry.max_backoff_ms %v must not be less than retry.base_backoff_ms %v", retry.MaxBackoff.Milliseconds(), retry.BaseBackoff.Milliseconds()))
+	}
+	if a.Retry.Jitter < 0 || a.Retry.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("retry.jitter %v must be within [0, 1]", a.Retry.Jitter))
+	}
+	if a.CircuitBreaker.FailureRatio < 0 || a.CircuitBreaker.FailureRatio > 1 {
+		errs = append(errs, fmt.Errorf("circuit_breaker.failure_ratio %v must be within [0, 1]", a.CircuitBreaker.FailureRatio))
+	}
+	return append(errs, validateResources(a.Resources)...)
+}
+
+//configError aggregates all problems of config, found at startup
+type configError []error
+
+func (c configError) Error() string {
+	messages := make([]string, 0, len(c))
+	for _, err := range c {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%v problem(s) found: %v", len(c), strings.Join(messages, "; "))
+}
+
+//getConfig merges sources of config, each next overrides previous: defaults < file < environment < overrides.
+//File is optional, if it's not required and doesn't exist.
+func getConfig(filename string, fileRequired bool, lookup envLookup, overrides ...func(cfg *appCfg)) (*appCfg, error) {
+	cfg := defaultConfig()
+	errs := []error{}
+	cfgData, err := ioutil.ReadFile(filename)
+	if err == nil {
+		err = json.Unmarshal(cfgData, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file '%v': %v", filename, err)
+		}
+		cfg.path = filename
+		errs = append(errs, cfg.loadSecrets()...)
+	} else if fileRequired || !os.IsNotExist(err) {
 		return nil, err
 	}
-	cfg := &appCfg{
-		redisPassword: redisPassword,
+	errs = append(errs, applyEnv(env_prefix, reflect.ValueOf(cfg).Elem(), lookup)...)
+	errs = append(errs, cfg.loadSecrets()...)
+	for _, override := range overrides {
+		override(cfg)
+	}
+	if len(cfg.Resources) == 0 {
+		cfg.Resources = []resourceCfg{cfg.legacyResource()}
+	}
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, configError(errs)
 	}
-	err = json.Unmarshal(cfgData, cfg)
-	return cfg, err
+	return cfg, nil
 }
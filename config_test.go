@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/utils"
+)
+
+type configFixture struct {
+	Dir    string
+	Path   string
+	Secret string
+}
+
+func newConfigFixture(t *testing.T) (*configFixture, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	f := &configFixture{
+		Dir:    dir,
+		Path:   filepath.Join(dir, "config.json"),
+		Secret: filepath.Join(dir, "secret"),
+	}
+	f.write(t, f.Secret, "secret from file\n")
+	return f, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func (f *configFixture) write(t *testing.T, path string, data string) {
+	err := ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+
+	t.Run("defaults < file < environment < flags", func(t *testing.T) {
+		f, cleanup := newConfigFixture(t)
+		defer cleanup()
+
+		f.write(t, f.Path, `{"api_url": "http://localhost", "app_timeout_seconds": 10, "redis": {"db": 1, "password": "file"}, "bind": {"port": 8081}}`)
+		cfg, err := getConfig(f.Path, true, fakeEnv(map[string]string{
+			"CACHESVC_REDIS_DB":       "2",
+			"CACHESVC_REDIS_PASSWORD": "env",
+		}), func(cfg *appCfg) {
+			cfg.Redis.Password = "flag"
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Redis.Address != default_redis_address || cfg.AppTimeoutSeconds != 10 || cfg.Bind.Port != 8081 {
+			t.Errorf("Defaults or file are not applied: %+v", cfg)
+		}
+		if cfg.Redis.DB != 2 || cfg.Redis.Password != "flag" {
+			t.Errorf("Environment or flags are not applied: %+v", cfg.Redis)
+		}
+		if cfg.path != f.Path || len(cfg.Resources) != 1 || cfg.Resources[0].Name != default_resource_name {
+			t.Errorf("Unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("secret is taken from the last source", func(t *testing.T) {
+		f, cleanup := newConfigFixture(t)
+		defer cleanup()
+
+		f.write(t, f.Path, `{"api_url": "http://localhost", "redis": {"password_file": "`+f.Secret+`"}, "upstream": {"api_key": "file"}}`)
+		cfg, err := getConfig(f.Path, true, fakeEnv(map[string]string{
+			"CACHESVC_REDIS_PASSWORD":        "env",
+			"CACHESVC_UPSTREAM_API_KEY_FILE": f.Secret,
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Redis.Password != "env" || cfg.Upstream.ApiKey != "secret from file" {
+			t.Errorf("Unexpected secrets: '%v', '%v'", cfg.Redis.Password, cfg.Upstream.ApiKey)
+		}
+	})
+
+	t.Run("file is optional, unless it's required", func(t *testing.T) {
+		f, cleanup := newConfigFixture(t)
+		defer cleanup()
+
+		_, err := getConfig(f.Path, true, fakeEnv(nil))
+		if err == nil {
+			t.Errorf("Expected error.")
+		}
+		cfg, err := getConfig(f.Path, false, fakeEnv(map[string]string{
+			"CACHESVC_API_URL": "http://localhost",
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(cfg.path) != 0 || cfg.Bind.Port != default_bind_port {
+			t.Errorf("Unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("all problems are reported at once", func(t *testing.T) {
+		f, cleanup := newConfigFixture(t)
+		defer cleanup()
+
+		f.write(t, f.Path, `{"api_url": "http://localhost", "cache_ttl_seconds": -1, "log": {"level": "loud"}}`)
+		_, err := getConfig(f.Path, true, fakeEnv(map[string]string{
+			"CACHESVC_BIND_PORT":               "http",
+			"CACHESVC_RETRY_JITTER":            "2",
+			"CACHESVC_READINESS_DRAIN_SECONDS": "120",
+			"CACHESVC_REDIS_PASSWORD_FILE":     filepath.Join(f.Dir, "missing"),
+		}))
+		errs, ok := err.(configError)
+		if !ok {
+			t.Fatalf("Expected config error. Got: %v", err)
+		}
+		if len(errs) != 6 {
+			t.Errorf("Expected 6 problems. Got: %v", err)
+		}
+	})
+
+	t.Run("broken file is an error", func(t *testing.T) {
+		f, cleanup := newConfigFixture(t)
+		defer cleanup()
+
+		f.write(t, f.Path, `{"redis":`)
+		_, err := getConfig(f.Path, true, fakeEnv(nil))
+		if err == nil {
+			t.Errorf("Expected error.")
+		}
+	})
+}
+
+func TestAppCfg_GetRetryPolicy(t *testing.T) {
+	cfg := defaultConfig()
+	policy := cfg.GetRetryPolicy()
+	if policy.MaxAttempts != 1 || policy.BaseBackoff != default_retry_base_backoff || policy.MaxBackoff != default_retry_max_backoff {
+		t.Errorf("Defaults are not applied: %+v", policy)
+	}
+	cfg.Retry.BaseBackoffMs = 10
+	cfg.Retry.MaxBackoffMs = 50
+	policy = cfg.GetRetryPolicy()
+	if policy.BaseBackoff != 10*time.Millisecond || policy.MaxBackoff != 50*time.Millisecond {
+		t.Errorf("Config is not applied: %+v", policy)
+	}
+}
+
+func TestAppCfg_Validate_Backoff(t *testing.T) {
+	cases := map[string]retryCfg{
+		"max less than base":     {BaseBackoffMs: 500, MaxBackoffMs: 100},
+		"base above default max": {BaseBackoffMs: 5000},
+		"default base above max": {MaxBackoffMs: 50},
+	}
+	for name, retry := range cases {
+		cfg := defaultConfig()
+		cfg.Api = "http://localhost"
+		cfg.Resources = []resourceCfg{cfg.legacyResource()}
+		cfg.Retry = retry
+		errs := cfg.validate()
+		if len(errs) != 1 {
+			t.Errorf("Case '%v'. Expected one problem. Got: %v", name, errs)
+		}
+	}
+}
+
+func TestBindCfg_GetTlsOptions(t *testing.T) {
+
+	t.Run("options are built from config", func(t *testing.T) {
+		b := bindCfg{
+			Ip:   "10.0.0.1",
+			Port: 8443,
+			Tls: tlsCfg{
+				CertFile:          "/etc/tls/tls.crt",
+				KeyFile:           "/etc/tls/tls.key",
+				MinVersion:        "1.3",
+				ClientCaFile:      "/etc/tls/ca.crt",
+				RequireClientCert: true,
+				CertCheckSeconds:  30,
+				RedirectPort:      8080,
+			},
+		}
+		opts, err := b.GetTlsOptions()
+		mocks.CmpError(t, err, nil)
+		expected := utils.TlsOptions{
+			CertFile:          "/etc/tls/tls.crt",
+			KeyFile:           "/etc/tls/tls.key",
+			MinVersion:        tls.VersionTLS13,
+			ClientCaFile:      "/etc/tls/ca.crt",
+			RequireClientCert: true,
+			CertCheckInterval: 30 * time.Second,
+			RedirectAddress:   "10.0.0.1:8080",
+		}
+		if opts != expected {
+			t.Errorf("Expected: %+v. Got: %+v", expected, opts)
+		}
+	})
+
+	t.Run("redirect is disabled by default", func(t *testing.T) {
+		b := bindCfg{Port: 8443, Tls: tlsCfg{CertFile: "tls.crt", KeyFile: "tls.key"}}
+		opts, err := b.GetTlsOptions()
+		mocks.CmpError(t, err, nil)
+		if len(opts.RedirectAddress) > 0 || opts.MinVersion != 0 {
+			t.Errorf("Unexpected options: %+v", opts)
+		}
+	})
+
+	t.Run("invalid config is an error", func(t *testing.T) {
+		cases := map[string]tlsCfg{
+			"missing key":         {CertFile: "tls.crt"},
+			"missing certificate": {KeyFile: "tls.key"},
+			"unknown version":     {CertFile: "tls.crt", KeyFile: "tls.key", MinVersion: "1.4"},
+		}
+		for name, c := range cases {
+			b := bindCfg{Port: 8443, Tls: c}
+			_, err := b.GetTlsOptions()
+			if err == nil {
+				t.Errorf("Case '%v'. Expected error.", name)
+			}
+		}
+	})
+}
+
+func TestBindCfg_Validate(t *testing.T) {
+	certs := tlsCfg{CertFile: "tls.crt", KeyFile: "tls.key"}
+	withTls := func(change func(c *tlsCfg)) tlsCfg {
+		c := certs
+		change(&c)
+		return c
+	}
+	cases := map[string]struct {
+		bind     bindCfg
+		problems int
+	}{
+		"plain http":              {bindCfg{Port: 80}, 0},
+		"tls":                     {bindCfg{Port: 443, Tls: certs}, 0},
+		"tls with redirect":       {bindCfg{Port: 443, Tls: withTls(func(c *tlsCfg) { c.RedirectPort = 80 })}, 0},
+		"zero port":               {bindCfg{}, 1},
+		"port out of range":       {bindCfg{Port: 65536}, 1},
+		"redirect without tls":    {bindCfg{Port: 80, Tls: tlsCfg{RedirectPort: 8080}}, 1},
+		"client CA without tls":   {bindCfg{Port: 80, Tls: tlsCfg{ClientCaFile: "ca.crt"}}, 1},
+		"certificate without key": {bindCfg{Port: 443, Tls: tlsCfg{CertFile: "tls.crt"}}, 1},
+		"unknown version":         {bindCfg{Port: 443, Tls: withTls(func(c *tlsCfg) { c.MinVersion = "2.0" })}, 1},
+		"redirect to same port":   {bindCfg{Port: 443, Tls: withTls(func(c *tlsCfg) { c.RedirectPort = 443 })}, 1},
+		"redirect out of range":   {bindCfg{Port: 443, Tls: withTls(func(c *tlsCfg) { c.RedirectPort = -1 })}, 1},
+		"all at once":             {bindCfg{Port: -1, Tls: tlsCfg{KeyFile: "tls.key", RedirectPort: 70000}}, 3},
+	}
+	for name, c := range cases {
+		errs := c.bind.validate()
+		if len(errs) != c.problems {
+			t.Errorf("Case '%v'. Expected %v problems. Got: %v", name, c.problems, errs)
+		}
+	}
+
+	t.Run("admin port must not be used by bind", func(t *testing.T) {
+		for _, port := range []int{443, 80} {
+			cfg := defaultConfig()
+			cfg.Api = "http://localhost"
+			cfg.Resources = []resourceCfg{cfg.legacyResource()}
+			cfg.Bind = bindCfg{Port: 443, Tls: withTls(func(c *tlsCfg) { c.RedirectPort = 80 })}
+			cfg.Admin.Port = port
+			errs := cfg.validate()
+			if len(errs) != 1 {
+				t.Errorf("Port %v. Expected one problem. Got: %v", port, errs)
+			}
+		}
+	})
+}
@@ -2,5 +2,11 @@ package consts
 
 const (
 	HEADER_CONTENT_TYPE   = "Content-Type"
+	HEADER_CACHE_STATUS   = "X-Cache-Status"
+	HEADER_API_KEY        = "autopilotapikey"
+	HEADER_REQUEST_ID     = "X-Request-ID"
 	MIME_APPLICATION_JSON = "application/json"
+	CACHE_STATUS_STALE    = "STALE"
+	CACHE_STATUS_HIT      = "HIT"
+	CACHE_STATUS_MISS     = "MISS"
 )
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	env_prefix = "CACHESVC"
+)
+
+//envLookup has the same signature as os.LookupEnv, so environment can be replaced
+type envLookup func(key string) (string, bool)
+
+//envName builds name of variable from json tags: bind.port -> CACHESVC_BIND_PORT
+func envName(prefix string, tag string) string {
+	return prefix + "_" + strings.ToUpper(tag)
+}
+
+//applyEnv overrides fields of cfg with environment variables. Nested structures are walked, so every field has own
+//variable. Errors are collected, so all invalid variables are reported at once.
+func applyEnv(prefix string, cfg reflect.Value, lookup envLookup) []error {
+	errs := []error{}
+	t := cfg.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(tag) == 0 || tag == "-" {
+			continue
+		}
+		name := envName(prefix, tag)
+		value := cfg.Field(i)
+		if value.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(name, value, lookup)...)
+			continue
+		}
+		env, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		err := setFromEnv(value, env)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", name, err))
+		}
+	}
+	return errs
+}
+
+//lists of strings and numbers are comma-separated, other complex values (like resources) are JSON
+func setFromEnv(value reflect.Value, env string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(env)
+		return nil
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(env), 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer '%v'", env)
+		}
+		value.SetInt(parsed)
+		return nil
+	case reflect.Float64, reflect.Float32:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(env), value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number '%v'", env)
+		}
+		value.SetFloat(parsed)
+		return nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(env))
+		if err != nil {
+			return fmt.Errorf("invalid boolean '%v'", env)
+		}
+		value.SetBool(parsed)
+		return nil
+	case reflect.Slice:
+		switch value.Type().Elem().Kind() {
+		case reflect.String, reflect.Int:
+			return setListFromEnv(value, env)
+		}
+	}
+	err := json.Unmarshal([]byte(env), value.Addr().Interface())
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return nil
+}
+
+//empty variable sets empty list
+func setListFromEnv(value reflect.Value, env string) error {
+	items := []string{}
+	if len(strings.TrimSpace(env)) > 0 {
+		items = strings.Split(env, ",")
+	}
+	list := reflect.MakeSlice(value.Type(), len(items), len(items))
+	for i, item := range items {
+		err := setFromEnv(list.Index(i), strings.TrimSpace(item))
+		if err != nil {
+			return err
+		}
+	}
+	value.Set(list)
+	return nil
+}
+
+//secret is a value, that can be read from file instead, for example, from mounted Docker/Kubernetes secret
+type secret struct {
+	name  string
+	value *string
+	file  *string
+}
+
+//if both value and file are set in the same source, file is used
+func (s *secret) load() error {
+	if len(*s.file) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(*s.file)
+	if err != nil {
+		return fmt.Errorf("failed to read %v from file: %v", s.name, err)
+	}
+	*s.value = strings.TrimRight(string(data), "\r\n")
+	*s.file = ""
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+//fakeEnv replaces environment of process in tests
+func fakeEnv(vars map[string]string) envLookup {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+type envTestCfg struct {
+	Name    string   `json:"name"`
+	Count   int      `json:"count"`
+	Ratio   float64  `json:"ratio"`
+	Enabled bool     `json:"enabled"`
+	Headers []string `json:"headers"`
+	Codes   []int    `json:"codes"`
+	Nested  struct {
+		Port int `json:"port"`
+	} `json:"nested"`
+	Items    []resourceCfg `json:"items"`
+	Ignored  string        `json:"-"`
+	NoTag    string
+	internal string
+}
+
+func TestApplyEnv(t *testing.T) {
+
+	t.Run("every field has own variable", func(t *testing.T) {
+		cfg := envTestCfg{Count: 1, NoTag: "kept", Headers: []string{"a"}}
+		errs := applyEnv("TEST", reflect.ValueOf(&cfg).Elem(), fakeEnv(map[string]string{
+			"TEST_NAME":        "name",
+			"TEST_RATIO":       " 0.5",
+			"TEST_ENABLED":     "true",
+			"TEST_HEADERS":     "",
+			"TEST_CODES":       "429, 503",
+			"TEST_NESTED_PORT": "9090",
+			"TEST_ITEMS":       `[{"name": "contact", "path": "/contact"}]`,
+			"TEST_IGNORED":     "value",
+			"TEST_NOTAG":       "value",
+		}))
+		if len(errs) > 0 {
+			t.Fatalf("Unexpected errors: %v", errs)
+		}
+		expected := envTestCfg{
+			Name:    "name",
+			Count:   1,
+			Ratio:   0.5,
+			Enabled: true,
+			Headers: []string{},
+			Codes:   []int{429, 503},
+			Items:   []resourceCfg{{Name: "contact", Path: "/contact"}},
+			NoTag:   "kept",
+		}
+		expected.Nested.Port = 9090
+		if !reflect.DeepEqual(cfg, expected) {
+			t.Errorf("Expected: %+v. Got: %+v", expected, cfg)
+		}
+	})
+
+	t.Run("all invalid variables are reported", func(t *testing.T) {
+		cfg := envTestCfg{}
+		errs := applyEnv("TEST", reflect.ValueOf(&cfg).Elem(), fakeEnv(map[string]string{
+			"TEST_COUNT":       "many",
+			"TEST_RATIO":       "half",
+			"TEST_ENABLED":     "sure",
+			"TEST_CODES":       "429,x",
+			"TEST_NESTED_PORT": "80.5",
+			"TEST_ITEMS":       "contact",
+		}))
+		if len(errs) != 6 {
+			t.Errorf("Expected 6 errors. Got: %v", errs)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"time"
+
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/utils"
+)
+
+//listeners are public listener of API and optional admin listener of ops endpoints
+type listeners struct {
+	api   utils.Service
+	admin utils.Service
+}
+
+//nil channel is never ready, so missing admin listener never fails
+func (l *listeners) adminFailed() <-chan error {
+	if l.admin == nil {
+		return nil
+	}
+	return l.admin.Failed()
+}
+
+func (l *listeners) stop(logger logs.Logger) {
+	stopService(l.api, logger)
+	if l.admin != nil {
+		stopService(l.admin, logger)
+	}
+}
+
+//drain waits for delay, unless a listener fails earlier
+func (rt *runtime) drain(delay time.Duration, l listeners) {
+	if delay <= 0 {
+		return
+	}
+	rt.logger.Infof("Draining for %v.", delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case err := <-l.api.Failed():
+		rt.logger.Errorf("Service failed while draining. Error: %v", err)
+	case err := <-l.adminFailed():
+		rt.logger.Errorf("Admin service failed while draining. Error: %v", err)
+	}
+}
+
+//listen starts listeners, which addresses differ from previous config (all of them, if there is no previous config).
+//If any of them fails, already started ones are stopped and current listeners are kept.
+func (rt *runtime) listen(cfg *appCfg, prev *appCfg, current listeners) (listeners, error) {
+	next := current
+	apiChanged := prev == nil || cfg.GetBind() != prev.GetBind()
+	adminChanged := prev == nil || cfg.Admin != prev.Admin
+	if apiChanged {
+		srv, err := newService(cfg, rt.handler, rt.logger)
+		if err != nil {
+			return current, err
+		}
+		next.api = srv
+		rt.logger.Infof("Listening at '%s'", cfg.GetBind())
+	}
+	if adminChanged {
+		next.admin = nil
+		if cfg.Admin.IsEnabled() {
+			srv, err := utils.NewService(cfg.Admin.GetBind(), rt.adminHandler)
+			if err != nil {
+				if apiChanged {
+					stopService(next.api, rt.logger)
+				}
+				return current, err
+			}
+			next.admin = srv
+			rt.logger.Infof("Admin endpoints are listening at '%s'", cfg.Admin.GetBind())
+		}
+	}
+	if apiChanged && current.api != nil {
+		go stopService(current.api, rt.logger)
+	}
+	if adminChanged && current.admin != nil {
+		go stopService(current.admin, rt.logger)
+	}
+	return next, nil
+}
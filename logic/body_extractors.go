@@ -0,0 +1,97 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/schema"
+)
+
+const (
+	json_mime_suffix = "+json"
+)
+
+//limitedBody reads one byte more than allowed, so it's clear, whether body was cut
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func newTooLargeError(maxBytes int64) error {
+	return NewError(ErrorPayloadTooLarge, fmt.Sprintf("request body is larger than %v bytes", maxBytes), nil)
+}
+
+//NewBodyLimitExtractor fails with 413, if body is larger than maxBytes. maxBytes <= 0 means no limit.
+func NewBodyLimitExtractor(maxBytes int64, getData RequestDataExtractor) RequestDataExtractor {
+	return func(r *http.Request) ([]byte, error) {
+		if maxBytes <= 0 || r == nil || r.Body == nil {
+			return getData(r)
+		}
+		if r.ContentLength > maxBytes {
+			return nil, newTooLargeError(maxBytes)
+		}
+		r.Body = &limitedBody{
+			Reader: io.LimitReader(r.Body, maxBytes+1),
+			Closer: r.Body,
+		}
+		data, err := getData(r)
+		if err != nil {
+			return data, err
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, newTooLargeError(maxBytes)
+		}
+		return data, nil
+	}
+}
+
+//isJsonContentType accepts application/json and structured types like application/merge-patch+json
+func isJsonContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == consts.MIME_APPLICATION_JSON || strings.HasSuffix(mediaType, json_mime_suffix)
+}
+
+//NewJsonBodyExtractor fails with 415, if body is not declared as JSON, and with 400, if it's not a valid JSON.
+//It only checks syntax, body is passed as is.
+func NewJsonBodyExtractor(getData RequestDataExtractor) RequestDataExtractor {
+	return func(r *http.Request) ([]byte, error) {
+		if r != nil && !isJsonContentType(r.Header.Get(consts.HEADER_CONTENT_TYPE)) {
+			return nil, NewError(ErrorUnsupportedMediaType, fmt.Sprintf("content type must be %v", consts.MIME_APPLICATION_JSON), nil)
+		}
+		data, err := getData(r)
+		if err != nil {
+			return data, err
+		}
+		if !json.Valid(data) {
+			return nil, NewError(ErrorBadRequest, "request body is not a valid JSON", nil)
+		}
+		return data, nil
+	}
+}
+
+//NewSchemaExtractor fails with 422 and list of violations, if body doesn't match schema.
+//Body has to be a valid JSON, so it's expected to be used on top of NewJsonBodyExtractor.
+func NewSchemaExtractor(s *schema.Schema, getData RequestDataExtractor) RequestDataExtractor {
+	return func(r *http.Request) ([]byte, error) {
+		data, err := getData(r)
+		if err != nil {
+			return data, err
+		}
+		violations, err := s.Validate(data)
+		if err != nil {
+			return nil, NewError(ErrorBadRequest, "request body is not a valid JSON", err)
+		}
+		if len(violations) > 0 {
+			return nil, NewValidationError(violations)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,158 @@
+package logic
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logic"
+	"github.com/coldze/test/schema"
+)
+
+func newJsonRequest(body string, contentType string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "https://test.com.au", strings.NewReader(body))
+	if len(contentType) > 0 {
+		r.Header.Set(consts.HEADER_CONTENT_TYPE, contentType)
+	}
+	return r
+}
+
+func TestNewBodyLimitExtractor(t *testing.T) {
+	t.Run("body within limit is returned", func(t *testing.T) {
+		getData := NewBodyLimitExtractor(5, GetRequestBodyData)
+		data, err := getData(newJsonRequest("12345", ""))
+		mocks.CmpError(t, err, nil)
+		if string(data) != "12345" {
+			t.Errorf("Unexpected data: %v", string(data))
+		}
+	})
+
+	t.Run("body larger than limit is a failure", func(t *testing.T) {
+		getData := NewBodyLimitExtractor(5, GetRequestBodyData)
+		r := newJsonRequest("123456", "")
+		//unknown length, so limit is checked while reading
+		r.ContentLength = -1
+		_, err := getData(r)
+		if KindOf(err) != ErrorPayloadTooLarge {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("declared length larger than limit is a failure without reading", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewBodyLimitExtractor(5, extractor.Extract)
+		_, err := getData(newJsonRequest("123456", ""))
+		if KindOf(err) != ErrorPayloadTooLarge {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		getData := NewBodyLimitExtractor(0, GetRequestBodyData)
+		data, err := getData(newJsonRequest(string(bytes.Repeat([]byte("a"), 100)), ""))
+		mocks.CmpError(t, err, nil)
+		if len(data) != 100 {
+			t.Errorf("Unexpected data length: %v", len(data))
+		}
+	})
+
+	t.Run("error of extractor is returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expErr := errors.New("some test error")
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewBodyLimitExtractor(5, extractor.Extract)
+		extractor.EXPECT().Extract(gomock.Any()).Return(nil, expErr).Times(1)
+		_, err := getData(newJsonRequest("123", ""))
+		mocks.CmpError(t, err, expErr)
+	})
+}
+
+func TestNewJsonBodyExtractor(t *testing.T) {
+	getData := NewJsonBodyExtractor(GetRequestBodyData)
+
+	t.Run("valid json is returned as is", func(t *testing.T) {
+		for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "application/merge-patch+json"} {
+			data, err := getData(newJsonRequest(`{"contact": {"Email": "test@test.com"}}`, contentType))
+			mocks.CmpError(t, err, nil)
+			if string(data) != `{"contact": {"Email": "test@test.com"}}` {
+				t.Errorf("Unexpected data: %v", string(data))
+			}
+		}
+	})
+
+	t.Run("not json content type is a failure", func(t *testing.T) {
+		for _, contentType := range []string{"", "text/plain", "application/jsonx", ";;"} {
+			_, err := getData(newJsonRequest(`{}`, contentType))
+			if KindOf(err) != ErrorUnsupportedMediaType {
+				t.Errorf("Content type '%v'. Unexpected error: %v", contentType, err)
+			}
+		}
+	})
+
+	t.Run("invalid json is a bad request", func(t *testing.T) {
+		for _, body := range []string{"", "{", `{"a": }`} {
+			_, err := getData(newJsonRequest(body, consts.MIME_APPLICATION_JSON))
+			if KindOf(err) != ErrorBadRequest {
+				t.Errorf("Body '%v'. Unexpected error: %v", body, err)
+			}
+		}
+	})
+}
+
+func TestNewSchemaExtractor(t *testing.T) {
+	s, err := schema.Parse([]byte(`{"type": "object", "required": ["contact"], "properties": {"contact": {"type": "object"}}}`))
+	mocks.CmpError(t, err, nil)
+
+	t.Run("valid body is returned", func(t *testing.T) {
+		getData := NewSchemaExtractor(s, GetRequestBodyData)
+		data, err := getData(newJsonRequest(`{"contact": {}}`, ""))
+		mocks.CmpError(t, err, nil)
+		if string(data) != `{"contact": {}}` {
+			t.Errorf("Unexpected data: %v", string(data))
+		}
+	})
+
+	t.Run("violations are returned", func(t *testing.T) {
+		getData := NewSchemaExtractor(s, GetRequestBodyData)
+		_, err := getData(newJsonRequest(`{"contact": []}`, ""))
+		if KindOf(err) != ErrorValidationFailed {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		violations := violationsOf(err)
+		if len(violations) != 1 || violations[0].Field != "$.contact" {
+			t.Errorf("Unexpected violations: %v", violations)
+		}
+	})
+
+	t.Run("invalid json is a bad request", func(t *testing.T) {
+		getData := NewSchemaExtractor(s, GetRequestBodyData)
+		_, err := getData(newJsonRequest(`{`, ""))
+		if KindOf(err) != ErrorBadRequest {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error of extractor is returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expErr := errors.New("some test error")
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewSchemaExtractor(s, extractor.Extract)
+		extractor.EXPECT().Extract(gomock.Any()).Return(nil, expErr).Times(1)
+		_, err := getData(newJsonRequest("{}", ""))
+		mocks.CmpError(t, err, expErr)
+	})
+}
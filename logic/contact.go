@@ -1,12 +1,12 @@
 package logic
 
-import "encoding/json"
-
 type Contact struct {
 	ID string `json:"contact_id"`
 }
 
-func ParseContact(data []byte) (contact Contact, err error) {
-	err = json.Unmarshal(data, &contact)
-	return
+var parseDefaultContact, _ = NewContactParser([]string{default_key_field}, default_key_separator)
+
+//ParseContact takes key from top-level contact_id field
+func ParseContact(data []byte) (Contact, error) {
+	return parseDefaultContact(data)
 }
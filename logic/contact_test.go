@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -17,6 +18,13 @@ func TestParseContact(t *testing.T) {
 		}
 	})
 
+	t.Run("missing contact id is a failure", func(t *testing.T) {
+		_, err := ParseContact([]byte("{\"contact\": {}}"))
+		if !errors.Is(err, ErrKeyMissing) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
 	t.Run("correct json is parsed", func(t *testing.T) {
 		id := "test_id"
 		data := fmt.Sprintf("{\"contact_id\": \"%v\"}", id)
@@ -5,4 +5,5 @@ import "net/http"
 type DataBuilder interface {
 	http.ResponseWriter
 	Build() ([]byte, error)
+	StatusCode() int
 }
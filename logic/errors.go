@@ -0,0 +1,204 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/coldze/test/schema"
+)
+
+type ErrorKind int
+
+const (
+	ErrorInternal ErrorKind = iota
+	ErrorBadRequest
+	ErrorNotFound
+	ErrorUpstreamFailed
+	ErrorUpstreamUnavailable
+	ErrorUpstreamTimeout
+	ErrorPayloadTooLarge
+	ErrorUnsupportedMediaType
+	ErrorValidationFailed
+	ErrorUnauthorized
+	ErrorUpstreamRejected
+	ErrorCanceled
+)
+
+const (
+	internal_error_message = "internal error"
+
+	//not a standard status, it's used by nginx for requests, that client abandoned
+	status_client_closed_request = 499
+)
+
+//StatusCode is a status of response, that client gets for error of this kind
+func (k ErrorKind) StatusCode() int {
+	switch k {
+	case ErrorBadRequest:
+		return http.StatusBadRequest
+	case ErrorNotFound:
+		return http.StatusNotFound
+	case ErrorUpstreamFailed:
+		return http.StatusBadGateway
+	case ErrorUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorUpstreamTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case ErrorValidationFailed:
+		return http.StatusUnprocessableEntity
+	case ErrorUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorUpstreamRejected:
+		return http.StatusBadRequest
+	case ErrorCanceled:
+		return status_client_closed_request
+	}
+	return http.StatusInternalServerError
+}
+
+//Code is a machine-readable code of error in response body
+func (k ErrorKind) Code() string {
+	switch k {
+	case ErrorBadRequest:
+		return "bad_request"
+	case ErrorNotFound:
+		return "not_found"
+	case ErrorUpstreamFailed:
+		return "upstream_error"
+	case ErrorUpstreamUnavailable:
+		return "upstream_unavailable"
+	case ErrorUpstreamTimeout:
+		return "upstream_timeout"
+	case ErrorPayloadTooLarge:
+		return "payload_too_large"
+	case ErrorUnsupportedMediaType:
+		return "unsupported_media_type"
+	case ErrorValidationFailed:
+		return "validation_failed"
+	case ErrorUnauthorized:
+		return "unauthorized"
+	case ErrorUpstreamRejected:
+		return "upstream_rejected"
+	case ErrorCanceled:
+		return "canceled"
+	}
+	return "internal"
+}
+
+func (k ErrorKind) String() string {
+	return k.Code()
+}
+
+//Error is returned by data-sources, when it's clear, what went wrong. Message and Violations are shown to client,
+//Cause is only logged. If Status is set, client gets it instead of status of kind (e.g. status of external API).
+type Error struct {
+	Kind       ErrorKind
+	Message    string
+	Cause      error
+	Violations []schema.Violation
+	Status     int
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func NewError(kind ErrorKind, message string, cause error) error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Cause:   cause,
+	}
+}
+
+//NewErrorWithStatus answers client with status, that differs from status of kind, body has the same code and message
+func NewErrorWithStatus(kind ErrorKind, message string, cause error, status int) error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Cause:   cause,
+		Status:  status,
+	}
+}
+
+func NewValidationError(violations []schema.Violation) error {
+	return &Error{
+		Kind:       ErrorValidationFailed,
+		Message:    "request body doesn't match schema",
+		Violations: violations,
+	}
+}
+
+//NewUpstreamError classifies failed call to external API: timeouts and calls, abandoned by client, are separated from
+//other failures
+func NewUpstreamError(message string, cause error) error {
+	if errors.Is(cause, context.Canceled) {
+		return NewError(ErrorCanceled, message, cause)
+	}
+	if isTimeout(cause) {
+		return NewError(ErrorUpstreamTimeout, message, cause)
+	}
+	return NewError(ErrorUpstreamFailed, message, cause)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+//KindOf returns kind of the first typed error in chain, untyped errors are internal
+func KindOf(err error) ErrorKind {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	if isTimeout(err) {
+		return ErrorUpstreamTimeout
+	}
+	return ErrorInternal
+}
+
+//messageOf hides details of internal errors from client
+func messageOf(err error) string {
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Kind == ErrorInternal {
+		return internal_error_message
+	}
+	return typed.Message
+}
+
+func violationsOf(err error) []schema.Violation {
+	var typed *Error
+	if !errors.As(err, &typed) {
+		return nil
+	}
+	return typed.Violations
+}
+
+//StatusOf is a status of response, that client gets for error
+func StatusOf(err error) int {
+	var typed *Error
+	if errors.As(err, &typed) && typed.Status > 0 {
+		return typed.Status
+	}
+	return KindOf(err).StatusCode()
+}
@@ -0,0 +1,94 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestKindOf(t *testing.T) {
+	cause := errors.New("some test error")
+	cases := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{"untyped", cause, ErrorInternal},
+		{"typed", NewError(ErrorNotFound, "test", cause), ErrorNotFound},
+		{"wrapped typed", fmt.Errorf("wrapped: %w", NewError(ErrorBadRequest, "test", nil)), ErrorBadRequest},
+		{"deadline", context.DeadlineExceeded, ErrorUpstreamTimeout},
+		{"upstream timeout", NewUpstreamError("test", timeoutError{}), ErrorUpstreamTimeout},
+		{"upstream failure", NewUpstreamError("test", cause), ErrorUpstreamFailed},
+		{"canceled", context.Canceled, ErrorCanceled},
+		{"upstream canceled", NewUpstreamError("test", fmt.Errorf("wrapped: %w", context.Canceled)), ErrorCanceled},
+	}
+	for _, c := range cases {
+		res := KindOf(c.err)
+		if res != c.expected {
+			t.Errorf("Case '%v'. Expected: %v. Got: %v", c.name, c.expected, res)
+		}
+	}
+}
+
+func TestErrorKind_StatusCode(t *testing.T) {
+	cases := map[ErrorKind]int{
+		ErrorInternal:             http.StatusInternalServerError,
+		ErrorBadRequest:           http.StatusBadRequest,
+		ErrorNotFound:             http.StatusNotFound,
+		ErrorUpstreamFailed:       http.StatusBadGateway,
+		ErrorUpstreamUnavailable:  http.StatusServiceUnavailable,
+		ErrorUpstreamTimeout:      http.StatusGatewayTimeout,
+		ErrorPayloadTooLarge:      http.StatusRequestEntityTooLarge,
+		ErrorUnsupportedMediaType: http.StatusUnsupportedMediaType,
+		ErrorValidationFailed:     http.StatusUnprocessableEntity,
+		ErrorUnauthorized:         http.StatusUnauthorized,
+		ErrorUpstreamRejected:     http.StatusBadRequest,
+		ErrorCanceled:             status_client_closed_request,
+	}
+	for kind, expected := range cases {
+		if kind.StatusCode() != expected {
+			t.Errorf("Kind %v. Expected: %v. Got: %v", kind, expected, kind.StatusCode())
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("some test error")
+	err := NewError(ErrorUpstreamFailed, "test message", cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("Cause is not unwrapped.")
+	}
+	if err.Error() != "test message: some test error" {
+		t.Errorf("Unexpected message: %v", err.Error())
+	}
+	if NewError(ErrorNotFound, "test message", nil).Error() != "test message" {
+		t.Errorf("Unexpected message without cause.")
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"untyped", errors.New("some test error"), http.StatusInternalServerError},
+		{"status of kind", NewError(ErrorUpstreamRejected, "test", nil), http.StatusBadRequest},
+		{"own status", NewErrorWithStatus(ErrorUpstreamRejected, "test", nil, http.StatusConflict), http.StatusConflict},
+		{"wrapped", fmt.Errorf("wrapped: %w", NewErrorWithStatus(ErrorUpstreamRejected, "test", nil, http.StatusForbidden)), http.StatusForbidden},
+	}
+	for _, c := range cases {
+		res := StatusOf(c.err)
+		if res != c.expected {
+			t.Errorf("Case '%v'. Expected: %v. Got: %v", c.name, c.expected, res)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -29,3 +30,14 @@ func GetResponseBodyData(r *http.Response) ([]byte, error) {
 	}
 	return getBodyData(r.Body)
 }
+
+//NewRequiredHeaderExtractor fails with 401, if request has no credentials of a caller in header. Request is rejected
+//before body is read.
+func NewRequiredHeaderExtractor(name string, getData RequestDataExtractor) RequestDataExtractor {
+	return func(r *http.Request) ([]byte, error) {
+		if r != nil && len(r.Header.Get(name)) == 0 {
+			return nil, NewError(ErrorUnauthorized, fmt.Sprintf("%v header is required", name), nil)
+		}
+		return getData(r)
+	}
+}
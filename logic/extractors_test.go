@@ -3,6 +3,7 @@ package logic
 import (
 	"bytes"
 	"errors"
+	"github.com/coldze/test/mocks/mock_logic"
 	"github.com/coldze/test/mocks/mock_std"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
@@ -276,3 +277,32 @@ func TestGetResponseBodyData(t *testing.T) {
 		}
 	})
 }
+
+func TestNewRequiredHeaderExtractor(t *testing.T) {
+	t.Run("request without header is unauthorized", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewRequiredHeaderExtractor("autopilotapikey", extractor.Extract)
+		_, err := getData(httptest.NewRequest(http.MethodGet, "https://test.com.au", nil))
+		if KindOf(err) != ErrorUnauthorized {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("request with header is passed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewRequiredHeaderExtractor("autopilotapikey", extractor.Extract)
+		r := httptest.NewRequest(http.MethodGet, "https://test.com.au", nil)
+		r.Header.Set("autopilotapikey", "some-key")
+		extractor.EXPECT().Extract(r).Return([]byte("some-id"), nil).Times(1)
+		data, err := getData(r)
+		if err != nil || string(data) != "some-id" {
+			t.Errorf("Unexpected result: %v, %v", string(data), err)
+		}
+	})
+}
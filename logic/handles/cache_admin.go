@@ -0,0 +1,29 @@
+package handles
+
+import (
+	"net/http"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/sources"
+)
+
+func NewCacheInspectHandler(loggerFactory LoggerFactory, admin sources.CacheAdmin, getKey logic.RequestDataExtractor) http.HandlerFunc {
+	handler := newHttpHandler(getKey, admin.Inspect)
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
+
+func NewCacheDeleteHandler(loggerFactory LoggerFactory, admin sources.CacheAdmin, getKey logic.RequestDataExtractor) http.HandlerFunc {
+	handler := newHttpHandler(getKey, admin.Delete)
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
+
+func NewCachePurgeHandler(loggerFactory LoggerFactory, admin sources.CacheAdmin, getData logic.RequestDataExtractor) http.HandlerFunc {
+	handler := newHttpHandler(getData, admin.Purge)
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
+
+//headers of request are passed to data-source, so values are warmed in partition of the caller (e.g. by API key)
+func NewCacheWarmHandler(loggerFactory LoggerFactory, warm sources.CacheWarmer, getData logic.RequestDataExtractor) http.HandlerFunc {
+	handler := newHttpHandler(getData, logicHandler(warm))
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
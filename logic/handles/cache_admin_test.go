@@ -0,0 +1,35 @@
+package handles
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/mocks/mock_handles"
+	"github.com/coldze/test/mocks/mock_logic"
+	"github.com/coldze/test/mocks/mock_sources"
+)
+
+func TestNewCacheAdminHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	loggerFactory := mock_handles.NewMockLoggerFactory(ctrl)
+	admin := mock_sources.NewMockCacheAdmin(ctrl)
+	dataSource := mock_sources.NewMockDataSource(ctrl)
+	getData := mock_logic.NewMockRequestDataExtractor(ctrl)
+
+	if NewCacheInspectHandler(loggerFactory.Create, admin, getData.Extract) == nil {
+		t.Errorf("Cache inspect handler is nil")
+	}
+	if NewCacheDeleteHandler(loggerFactory.Create, admin, getData.Extract) == nil {
+		t.Errorf("Cache delete handler is nil")
+	}
+	if NewCachePurgeHandler(loggerFactory.Create, admin, getData.Extract) == nil {
+		t.Errorf("Cache purge handler is nil")
+	}
+	if NewCacheWarmHandler(loggerFactory.Create, sources.NewCacheWarmer(dataSource, 1), getData.Extract) == nil {
+		t.Errorf("Cache warm handler is nil")
+	}
+}
@@ -2,6 +2,7 @@ package handles
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/coldze/test/logic"
@@ -23,32 +24,23 @@ func newHttpHandler(getData logic.RequestDataExtractor, handler logicHandler) ht
 				}
 			}()
 		}
-		defer func() {
-			r := recover()
-			if r == nil {
-				return
-			}
-			err, ok := r.(error)
-			if ok {
-				logger.Errorf("Panic occurred in handler. Error: %v", err)
-				return
-			}
-			logger.Errorf("Panic occurred in handler. Unknown error: %+v. Type: %T.", r, r)
-		}()
 		data, err := getData(r)
 		if err != nil {
-			logger.Errorf("Failed to read body. Error: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			var typed *logic.Error
+			if !errors.As(err, &typed) {
+				err = logic.NewError(logic.ErrorBadRequest, "failed to read request", err)
+			}
+			writeError(ctx, w, err)
 			return
 		}
 		ctx = utils.SetHeaders(ctx, r.Header)
 		res, err := handler(ctx, data)
 		if err != nil {
-			logger.Errorf("Failed to process. Error: %v", err)
+			writeError(ctx, w, err)
+			return
 		}
 		if res == nil {
-			logger.Errorf("Response is empty.")
-			w.WriteHeader(http.StatusInternalServerError)
+			writeError(ctx, w, errors.New("response is empty"))
 			return
 		}
 		err = res.Write(w)
@@ -57,3 +49,27 @@ func newHttpHandler(getData logic.RequestDataExtractor, handler logicHandler) ht
 		}
 	}
 }
+
+//writeError answers with a JSON error body, errors of client are not errors of service, so they are only warnings.
+//Request, abandoned by client, is not an error at all.
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	logger := utils.GetLogger(ctx)
+	kind := logic.KindOf(err)
+	if kind == logic.ErrorCanceled {
+		logger.Infof("Request is canceled by client. Error: %v", err)
+	} else if kind.StatusCode() < http.StatusInternalServerError {
+		logger.Warningf("Failed to process. Error: %v", err)
+	} else {
+		logger.Errorf("Failed to process. Error: %v", err)
+	}
+	res, rErr := logic.NewErrorResponse(err, utils.GetRequestId(ctx))
+	if rErr != nil {
+		logger.Errorf("Failed to create error response. Error: %v", rErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rErr = res.Write(w)
+	if rErr != nil {
+		logger.Errorf("Failed to write response. Error: %v", rErr)
+	}
+}
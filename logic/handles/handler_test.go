@@ -2,7 +2,11 @@ package handles
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logic"
 	"github.com/coldze/test/mocks/mock_handles"
 	"github.com/coldze/test/mocks/mock_logic"
 	"github.com/coldze/test/mocks/mock_logs"
@@ -28,6 +32,7 @@ type handlerFixture struct {
 	CtxWithHeader context.Context
 	Data          string
 	Error         error
+	RequestId     string
 	Response      *mocks.MockResponse
 	Logger        *mock_logs.MockLogger
 	GetData       *mock_logic.MockRequestDataExtractor
@@ -46,6 +51,7 @@ func newHandlerFixture(ctrl *gomock.Controller) *handlerFixture {
 		CtxWithHeader: ctxWithHeader,
 		Data:          "some random data",
 		Error:         errors.New("Some test error"),
+		RequestId:     "test-request-id",
 		Logger:        logger,
 		Response:      mocks.NewMockResponse(ctrl),
 		LogicHandler:  mock_handles.NewMockLogicHandler(ctrl),
@@ -60,134 +66,195 @@ func newRequest(ctx context.Context, method string, url string, body io.Reader,
 	return r.WithContext(ctx)
 }
 
+//errorMatcher matches errors, which wrap expected one
+type errorMatcher struct {
+	err error
+}
+
+func (m errorMatcher) Matches(x interface{}) bool {
+	err, ok := x.(error)
+	return ok && errors.Is(err, m.err)
+}
+
+func (m errorMatcher) String() string {
+	return fmt.Sprintf("wraps %v", m.err)
+}
+
+func checkErrorBody(t *testing.T, w *httptest.ResponseRecorder, status int, code string, message string, requestId string) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("Unexpected status code: %v", w.Code)
+	}
+	if w.Header().Get(consts.HEADER_CONTENT_TYPE) != consts.MIME_APPLICATION_JSON {
+		t.Errorf("Unexpected headers: %+v", w.Header())
+	}
+	body := map[string]string{}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	mocks.CmpError(t, err, nil)
+	if body["code"] != code || body["message"] != message || body["request_id"] != requestId {
+		t.Errorf("Unexpected body: %v", w.Body.String())
+	}
+}
+
 func newTestableHttpHandler(f *handlerFixture) http.HandlerFunc {
 	return newHttpHandler(f.GetData.Extract, f.LogicHandler.Handle)
 }
 
 func TestHttpHandler(t *testing.T) {
-	t.Run("error while getting data is a failure", func(t *testing.T) {
+	t.Run("error while getting data is a bad request", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
 		httpHandler := newTestableHttpHandler(f)
 		r := httptest.NewRequest(http.MethodGet, f.Url, nil)
-		r = r.WithContext(f.Ctx)
+		r = r.WithContext(utils.SetRequestId(f.Ctx, f.RequestId))
+		w := httptest.NewRecorder()
 
 		f.GetData.EXPECT().Extract(r).Return(nil, f.Error).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
-		f.W.EXPECT().WriteHeader(http.StatusInternalServerError)
+		f.Logger.EXPECT().Warningf(gomock.Any(), errorMatcher{f.Error}).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, http.StatusBadRequest, "bad_request", "failed to read request", f.RequestId)
 	})
 
-	t.Run("handling error is a failure if response is empty", func(t *testing.T) {
+	t.Run("typed error while getting data is kept", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
 		httpHandler := newTestableHttpHandler(f)
-
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
+		w := httptest.NewRecorder()
+		expErr := logic.NewError(logic.ErrorNotFound, "not here", nil)
 
-		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(nil, f.Error).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any()).Times(1)
-		f.W.EXPECT().WriteHeader(http.StatusInternalServerError)
+		f.GetData.EXPECT().Extract(r).Return(nil, expErr).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), expErr).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, http.StatusNotFound, "not_found", "not here", "")
+	})
+
+	t.Run("handling error is mapped to status code", func(t *testing.T) {
+		cases := []struct {
+			kind   logic.ErrorKind
+			status int
+			code   string
+		}{
+			{logic.ErrorBadRequest, http.StatusBadRequest, "bad_request"},
+			{logic.ErrorNotFound, http.StatusNotFound, "not_found"},
+			{logic.ErrorUpstreamFailed, http.StatusBadGateway, "upstream_error"},
+			{logic.ErrorUpstreamUnavailable, http.StatusServiceUnavailable, "upstream_unavailable"},
+			{logic.ErrorUpstreamTimeout, http.StatusGatewayTimeout, "upstream_timeout"},
+		}
+		for _, c := range cases {
+			t.Run(c.code, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				f := newHandlerFixture(ctrl)
+				httpHandler := newTestableHttpHandler(f)
+				r := newRequest(utils.SetRequestId(f.Ctx, f.RequestId), http.MethodGet, f.Url, nil, http.Header{})
+				w := httptest.NewRecorder()
+				expErr := logic.NewError(c.kind, "test message", f.Error)
+
+				f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
+				f.LogicHandler.EXPECT().Handle(gomock.Any(), []byte(f.Data)).Return(f.Response, expErr).Times(1)
+				if c.status < http.StatusInternalServerError {
+					f.Logger.EXPECT().Warningf(gomock.Any(), expErr).Times(1)
+				} else {
+					f.Logger.EXPECT().Errorf(gomock.Any(), expErr).Times(1)
+				}
+
+				httpHandler(w, r)
+				checkErrorBody(t, w, c.status, c.code, "test message", f.RequestId)
+			})
+		}
 	})
 
-	t.Run("handling error is not a failure if response is not empty, but error is logged", func(t *testing.T) {
+	t.Run("untyped handling error is internal and details are hidden", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
 		httpHandler := newTestableHttpHandler(f)
-
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
+		w := httptest.NewRecorder()
 
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
 		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, f.Error).Times(1)
 		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
-		f.Response.EXPECT().Write(f.W).Return(nil).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, http.StatusInternalServerError, "internal", "internal error", "")
 	})
 
-	t.Run("handling error is not a failure if response is not empty, but error is logged, resp write error is not a failure", func(t *testing.T) {
+	t.Run("canceled request is not an error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
 		httpHandler := newTestableHttpHandler(f)
-
-		expErr2 := errors.New("Test 2")
-
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
+		w := httptest.NewRecorder()
+		expErr := logic.NewUpstreamError("failed to call external API", context.Canceled)
 
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, f.Error).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
-		f.Response.EXPECT().Write(f.W).Return(expErr2).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), expErr2)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(nil, expErr).Times(1)
+		f.Logger.EXPECT().Infof(gomock.Any(), expErr).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, 499, "canceled", "failed to call external API", "")
 	})
 
-	t.Run("resp write error is not a failure", func(t *testing.T) {
+	t.Run("status of error is answered with JSON body", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
 		httpHandler := newTestableHttpHandler(f)
-
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
+		w := httptest.NewRecorder()
+		expErr := logic.NewErrorWithStatus(logic.ErrorUpstreamRejected, "test message", nil, http.StatusForbidden)
 
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, nil).Times(1)
-		f.Response.EXPECT().Write(f.W).Return(f.Error).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(nil, expErr).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), expErr).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, http.StatusForbidden, "upstream_rejected", "test message", "")
 	})
 
-	t.Run("logs error on throw of error", func(t *testing.T) {
+	t.Run("empty response is internal error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newHandlerFixture(ctrl)
-		getData := func(arg *http.Request) ([]byte, error) {
-			_, _ = f.GetData.Extract(arg)
-			panic(f.Error)
-		}
-		httpHandler := newHttpHandler(getData, f.LogicHandler.Handle)
-
+		httpHandler := newTestableHttpHandler(f)
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
+		w := httptest.NewRecorder()
 
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(nil, nil).Times(1)
+		f.Logger.EXPECT().Errorf(gomock.Any(), gomock.Any()).Times(1)
 
-		httpHandler(f.W, r)
+		httpHandler(w, r)
+		checkErrorBody(t, w, http.StatusInternalServerError, "internal", "internal error", "")
 	})
 
-	t.Run("logs error on throw of unknown", func(t *testing.T) {
+	t.Run("resp write error is not a failure", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		unknownErr := "unknown error type"
 		f := newHandlerFixture(ctrl)
-		getData := func(arg *http.Request) ([]byte, error) {
-			_, _ = f.GetData.Extract(arg)
-			panic(unknownErr)
-		}
-		httpHandler := newHttpHandler(getData, f.LogicHandler.Handle)
+		httpHandler := newTestableHttpHandler(f)
 
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, nil, http.Header{})
 
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), unknownErr, unknownErr)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, nil).Times(1)
+		f.Response.EXPECT().Write(f.W).Return(f.Error).Times(1)
+		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error)
 
 		httpHandler(f.W, r)
 	})
@@ -196,20 +263,16 @@ func TestHttpHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		unknownErr := "unknown error type"
 		f := newHandlerFixture(ctrl)
-		getData := func(arg *http.Request) ([]byte, error) {
-			_, _ = f.GetData.Extract(arg)
-			panic(unknownErr)
-		}
-		httpHandler := newHttpHandler(getData, f.LogicHandler.Handle)
+		httpHandler := newTestableHttpHandler(f)
 		body := mock_std.NewMockReadCloser(ctrl)
 
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, body, http.Header{})
 
 		body.EXPECT().Close().Return(nil).Times(1)
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), unknownErr, unknownErr)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, nil).Times(1)
+		f.Response.EXPECT().Write(f.W).Return(nil).Times(1)
 
 		httpHandler(f.W, r)
 	})
@@ -218,13 +281,8 @@ func TestHttpHandler(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		unknownErr := "unknown error type"
 		f := newHandlerFixture(ctrl)
-		getData := func(arg *http.Request) ([]byte, error) {
-			_, _ = f.GetData.Extract(arg)
-			panic(unknownErr)
-		}
-		httpHandler := newHttpHandler(getData, f.LogicHandler.Handle)
+		httpHandler := newTestableHttpHandler(f)
 		body := mock_std.NewMockReadCloser(ctrl)
 
 		r := newRequest(f.Ctx, http.MethodGet, f.Url, body, http.Header{})
@@ -232,7 +290,8 @@ func TestHttpHandler(t *testing.T) {
 		body.EXPECT().Close().Return(f.Error).Times(1)
 		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
 		f.GetData.EXPECT().Extract(r).Return([]byte(f.Data), nil).Times(1)
-		f.Logger.EXPECT().Errorf(gomock.Any(), unknownErr, unknownErr)
+		f.LogicHandler.EXPECT().Handle(f.CtxWithHeader, []byte(f.Data)).Return(f.Response, nil).Times(1)
+		f.Response.EXPECT().Write(f.W).Return(nil).Times(1)
 
 		httpHandler(f.W, r)
 	})
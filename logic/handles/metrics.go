@@ -0,0 +1,32 @@
+package handles
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coldze/test/metrics"
+)
+
+type HandlerMetrics struct {
+	Requests *metrics.Histogram
+	Panics   *metrics.Counter
+}
+
+func NewHandlerMetrics(registry *metrics.Registry) *HandlerMetrics {
+	return &HandlerMetrics{
+		Requests: registry.NewHistogram("http_request_duration_seconds", "Duration of handled http-requests.", metrics.DefaultBuckets, "route", "method", "status"),
+		Panics:   registry.NewCounter("http_panics_total", "Number of panics, recovered in handlers.", "route"),
+	}
+}
+
+//NewInstrumentedHandler measures requests of a route. Route is a template (e.g. /v1/contact/{id}), not a path,
+//otherwise every contact id would create its own series.
+func NewInstrumentedHandler(m *HandlerMetrics, route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+		next(rec, r)
+		m.Requests.Observe(time.Since(start).Seconds(), route, r.Method, strconv.Itoa(rec.Status()))
+	}
+}
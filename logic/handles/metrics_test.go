@@ -0,0 +1,46 @@
+package handles
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coldze/test/metrics"
+)
+
+func TestNewInstrumentedHandler(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewHandlerMetrics(registry)
+	route := "/v1/contact/{id}"
+
+	notFound := NewInstrumentedHandler(m, route, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ok := NewInstrumentedHandler(m, route, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("some data"))
+	})
+	empty := NewInstrumentedHandler(m, route, func(w http.ResponseWriter, r *http.Request) {})
+
+	notFound(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/contact/1", nil))
+	rec := httptest.NewRecorder()
+	ok(rec, httptest.NewRequest(http.MethodGet, "/v1/contact/2", nil))
+	empty(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/v1/contact/3", nil))
+	if rec.Body.String() != "some data" {
+		t.Errorf("Response is not passed to client.")
+	}
+
+	buf := bytes.Buffer{}
+	_ = registry.Write(&buf)
+	for _, line := range []string{
+		`http_request_duration_seconds_count{route="/v1/contact/{id}",method="GET",status="404"} 1`,
+		`http_request_duration_seconds_count{route="/v1/contact/{id}",method="GET",status="200"} 1`,
+		`http_request_duration_seconds_count{route="/v1/contact/{id}",method="DELETE",status="200"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected line '%v' in metrics:\n%v", line, buf.String())
+		}
+	}
+}
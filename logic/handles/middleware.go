@@ -2,17 +2,71 @@ package handles
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logs"
 	"github.com/coldze/test/utils"
 )
 
+const (
+	max_request_id_length = 128
+	no_cache_status       = "-"
+)
+
+//incoming request id is accepted only if it's safe to put it into logs and headers
+func isValidRequestId(id string) bool {
+	if len(id) == 0 || len(id) > max_request_id_length {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func getRequestId(r *http.Request) string {
+	id := r.Header.Get(consts.HEADER_REQUEST_ID)
+	if isValidRequestId(id) {
+		return id
+	}
+	return uuid.New().String()
+}
+
+//newCheckAndSetLoggerMiddleware sets a logger with request id to context, returns request id in response
+//and writes an access-log line, when request is handled.
 func newCheckAndSetLoggerMiddleware(newLogger LoggerFactory, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r == nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		ctx := utils.SetLogger(r.Context(), newLogger())
-		next(w, r.WithContext(ctx))
+		start := time.Now()
+		requestId := getRequestId(r)
+		logger := newLogger().With(
+			logs.NewField("request_id", requestId),
+			logs.NewField("method", r.Method),
+			logs.NewField("path", r.URL.Path),
+		)
+		ctx := utils.SetLogger(r.Context(), logger)
+		ctx = utils.SetRequestId(ctx, requestId)
+		ctx = utils.WithCacheStatus(ctx)
+		w.Header().Set(consts.HEADER_REQUEST_ID, requestId)
+		rec := newStatusRecorder(w)
+		next(rec, r.WithContext(ctx))
+		cacheStatus := utils.GetCacheStatus(ctx)
+		if len(cacheStatus) == 0 {
+			cacheStatus = no_cache_status
+		}
+		logger.With(
+			logs.NewField("status", rec.Status()),
+			logs.NewField("bytes", rec.bytes),
+			logs.NewField("duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+			logs.NewField("cache", cacheStatus),
+		).Infof("Request handled.")
 	}
 }
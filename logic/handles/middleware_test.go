@@ -1,6 +1,11 @@
 package handles
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logs"
 	"github.com/coldze/test/mocks/mock_handles"
 	"github.com/coldze/test/mocks/mock_logs"
 	"github.com/coldze/test/mocks/mock_std"
@@ -12,23 +17,45 @@ import (
 )
 
 type middlewareFixture struct {
-	Url       string
-	W         *mock_std.MockResponseWriter
-	NewLogger *mock_handles.MockLoggerFactory
-	Logger    *mock_logs.MockLogger
-	Next      *mock_std.MockHttpHandlerFunc
-	Reader    *mock_std.MockReadCloser
+	Url           string
+	RequestId     string
+	W             *mock_std.MockResponseWriter
+	NewLogger     *mock_handles.MockLoggerFactory
+	Logger        *mock_logs.MockLogger
+	RequestLogger *mock_logs.MockLogger
+	AccessLogger  *mock_logs.MockLogger
+	Next          *mock_std.MockHttpHandlerFunc
+	Reader        *mock_std.MockReadCloser
 }
 
 func newMiddlewareFixture(ctrl *gomock.Controller) *middlewareFixture {
 	return &middlewareFixture{
-		Url:       "https://test.url.com/",
-		W:         mock_std.NewMockResponseWriter(ctrl),
-		NewLogger: mock_handles.NewMockLoggerFactory(ctrl),
-		Logger:    mock_logs.NewMockLogger(ctrl),
-		Next:      mock_std.NewMockHttpHandlerFunc(ctrl),
-		Reader:    mock_std.NewMockReadCloser(ctrl),
+		Url:           "https://test.url.com/",
+		RequestId:     "some-request-id",
+		W:             mock_std.NewMockResponseWriter(ctrl),
+		NewLogger:     mock_handles.NewMockLoggerFactory(ctrl),
+		Logger:        mock_logs.NewMockLogger(ctrl),
+		RequestLogger: mock_logs.NewMockLogger(ctrl),
+		AccessLogger:  mock_logs.NewMockLogger(ctrl),
+		Next:          mock_std.NewMockHttpHandlerFunc(ctrl),
+		Reader:        mock_std.NewMockReadCloser(ctrl),
+	}
+}
+
+//fieldsMatcher checks values of fields by keys, other fields are ignored
+type fieldsMatcher map[string]interface{}
+
+func (m fieldsMatcher) Matches(x interface{}) bool {
+	f, ok := x.(logs.Field)
+	if !ok {
+		return false
 	}
+	expected, ok := m[f.Key]
+	return !ok || expected == f.Value
+}
+
+func (m fieldsMatcher) String() string {
+	return fmt.Sprintf("fields with values %v", map[string]interface{}(m))
 }
 
 func TestHttpMiddleware(t *testing.T) {
@@ -42,18 +69,61 @@ func TestHttpMiddleware(t *testing.T) {
 		next := func(w http.ResponseWriter, r *http.Request) {
 			f.Next.Handle(w, r)
 			logger := utils.GetLogger(r.Context())
-			if logger != f.Logger {
+			if logger != f.RequestLogger {
 				t.Errorf("Not expected logger value: %v", logger)
 			}
+			if utils.GetRequestId(r.Context()) != f.RequestId {
+				t.Errorf("Not expected request id: %v", utils.GetRequestId(r.Context()))
+			}
+			utils.SetCacheStatus(r.Context(), consts.CACHE_STATUS_HIT)
+			w.WriteHeader(http.StatusNotFound)
 		}
 
 		handle := newCheckAndSetLoggerMiddleware(f.NewLogger.Create, next)
 		r := httptest.NewRequest(http.MethodGet, "https://test.url.com/", f.Reader)
+		r.Header.Set(consts.HEADER_REQUEST_ID, f.RequestId)
+		header := http.Header{}
 
 		f.NewLogger.EXPECT().Create().Return(f.Logger).Times(1)
-		f.Next.EXPECT().Create(f.W, gomock.Any()).Times(1)
+		f.Logger.EXPECT().With(fieldsMatcher{"request_id": f.RequestId}, fieldsMatcher{"method": http.MethodGet}, fieldsMatcher{"path": "/"}).Return(f.RequestLogger).Times(1)
+		f.W.EXPECT().Header().Return(header).Times(1)
+		f.Next.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1)
+		f.W.EXPECT().WriteHeader(http.StatusNotFound).Times(1)
+		f.RequestLogger.EXPECT().With(fieldsMatcher{"status": http.StatusNotFound}, fieldsMatcher{"bytes": 0}, gomock.Any(), fieldsMatcher{"cache": consts.CACHE_STATUS_HIT}).Return(f.AccessLogger).Times(1)
+		f.AccessLogger.EXPECT().Infof(gomock.Any()).Times(1)
 
 		handle(f.W, r)
+		if header.Get(consts.HEADER_REQUEST_ID) != f.RequestId {
+			t.Errorf("Request id is not returned: %v", header)
+		}
+	})
+
+	t.Run("request id is generated, if it's missing or invalid", func(t *testing.T) {
+		for _, id := range []string{"", "with space", strings.Repeat("a", 129)} {
+			ctrl := gomock.NewController(t)
+
+			f := newMiddlewareFixture(ctrl)
+			var requestId string
+			next := func(w http.ResponseWriter, r *http.Request) {
+				requestId = utils.GetRequestId(r.Context())
+			}
+
+			handle := newCheckAndSetLoggerMiddleware(f.NewLogger.Create, next)
+			r := httptest.NewRequest(http.MethodGet, "https://test.url.com/", nil)
+			r.Header.Set(consts.HEADER_REQUEST_ID, id)
+			rec := httptest.NewRecorder()
+
+			f.NewLogger.EXPECT().Create().Return(f.Logger).Times(1)
+			f.Logger.EXPECT().With(gomock.Any(), gomock.Any(), gomock.Any()).Return(f.RequestLogger).Times(1)
+			f.RequestLogger.EXPECT().With(fieldsMatcher{"status": http.StatusOK}, gomock.Any(), gomock.Any(), fieldsMatcher{"cache": no_cache_status}).Return(f.AccessLogger).Times(1)
+			f.AccessLogger.EXPECT().Infof(gomock.Any()).Times(1)
+
+			handle(rec, r)
+			if len(requestId) == 0 || requestId == id || rec.Header().Get(consts.HEADER_REQUEST_ID) != requestId {
+				t.Errorf("Request id is not generated for '%v': %v", id, requestId)
+			}
+			ctrl.Finish()
+		}
 	})
 
 	t.Run("failed if request is nil", func(t *testing.T) {
@@ -0,0 +1,14 @@
+package handles
+
+import (
+	"net/http"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/sources"
+)
+
+func NewPatchHandler(loggerFactory LoggerFactory, src sources.DataSource, getData logic.RequestDataExtractor) http.HandlerFunc {
+	lHandler := logicHandler(src.Patch)
+	handler := newHttpHandler(getData, lHandler)
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
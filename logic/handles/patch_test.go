@@ -0,0 +1,24 @@
+package handles
+
+import (
+	"github.com/coldze/test/mocks/mock_handles"
+	"github.com/coldze/test/mocks/mock_logic"
+	"github.com/coldze/test/mocks/mock_sources"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestNewPatchHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	loggerFactory := mock_handles.NewMockLoggerFactory(ctrl)
+	dataSource := mock_sources.NewMockDataSource(ctrl)
+	getData := mock_logic.NewMockRequestDataExtractor(ctrl)
+
+	handler := NewPatchHandler(loggerFactory.Create, dataSource, getData.Extract)
+	if handler == nil {
+		t.Errorf("Patch handler is nil")
+	}
+}
@@ -7,9 +7,8 @@ import (
 	"github.com/coldze/test/logic/sources"
 )
 
-func NewPostHandler(loggerFactory LoggerFactory, src sources.DataSource) http.HandlerFunc {
+func NewPostHandler(loggerFactory LoggerFactory, src sources.DataSource, getData logic.RequestDataExtractor) http.HandlerFunc {
 	lHandler := logicHandler(src.Create)
-	getData := logic.GetRequestBodyData
 	handler := newHttpHandler(getData, lHandler)
 	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
 }
@@ -7,9 +7,8 @@ import (
 	"github.com/coldze/test/logic/sources"
 )
 
-func NewPutHandler(loggerFactory LoggerFactory, src sources.DataSource) http.HandlerFunc {
+func NewPutHandler(loggerFactory LoggerFactory, src sources.DataSource, getData logic.RequestDataExtractor) http.HandlerFunc {
 	lHandler := logicHandler(src.Update)
-	getData := logic.GetRequestBodyData
 	handler := newHttpHandler(getData, lHandler)
 	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
 }
@@ -2,6 +2,7 @@ package handles
 
 import (
 	"github.com/coldze/test/mocks/mock_handles"
+	"github.com/coldze/test/mocks/mock_logic"
 	"github.com/coldze/test/mocks/mock_sources"
 	"testing"
 
@@ -14,8 +15,9 @@ func TestNewPutHandler(t *testing.T) {
 
 	loggerFactory := mock_handles.NewMockLoggerFactory(ctrl)
 	dataSource := mock_sources.NewMockDataSource(ctrl)
+	getData := mock_logic.NewMockRequestDataExtractor(ctrl)
 
-	handler := NewPutHandler(loggerFactory.Create, dataSource)
+	handler := NewPutHandler(loggerFactory.Create, dataSource, getData.Extract)
 	if handler == nil {
 		t.Errorf("Put handler is nil")
 	}
@@ -0,0 +1,124 @@
+package handles
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coldze/test/consts"
+)
+
+const (
+	status_up            = "up"
+	status_down          = "down"
+	status_ready         = "ready"
+	status_not_ready     = "not_ready"
+	status_shutting_down = "shutting_down"
+)
+
+type DependencyCheck func(ctx context.Context) error
+
+//Dependency is checked on every readiness request. Failure of required dependency makes service not ready,
+//failure of optional one is only reported.
+type Dependency struct {
+	Name     string
+	Required bool
+	Check    DependencyCheck
+}
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readinessStatus struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies,omitempty"`
+}
+
+//check doesn't wait longer than ctx allows, even if dependency ignores ctx
+func check(ctx context.Context, d Dependency) dependencyStatus {
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- d.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status := dependencyStatus{
+		Status:    status_up,
+		Required:  d.Required,
+		LatencyMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		status.Status = status_down
+		status.Error = err.Error()
+	}
+	return status
+}
+
+func checkAll(ctx context.Context, dependencies []Dependency) readinessStatus {
+	res := readinessStatus{
+		Status:       status_ready,
+		Dependencies: make(map[string]dependencyStatus, len(dependencies)),
+	}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(len(dependencies))
+	for _, d := range dependencies {
+		go func(d Dependency) {
+			defer wg.Done()
+			status := check(ctx, d)
+			lock.Lock()
+			defer lock.Unlock()
+			res.Dependencies[d.Name] = status
+			if d.Required && status.Status != status_up {
+				res.Status = status_not_ready
+			}
+		}(d)
+	}
+	wg.Wait()
+	return res
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+//NewReadyHandler reports status of dependencies as JSON. It returns 503, when any required dependency is down
+//or when service is shutting down (stopping is closed), so no new traffic is routed to it.
+func NewReadyHandler(dependencies []Dependency, timeout time.Duration, stopping <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := readinessStatus{
+			Status: status_shutting_down,
+		}
+		if !isClosed(stopping) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			res = checkAll(ctx, dependencies)
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+		if res.Status != status_ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,94 @@
+package handles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveReady(t *testing.T, handler http.HandlerFunc) (int, readinessStatus) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	res := readinessStatus{}
+	err := json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Errorf("Failed to parse response: %v", err)
+	}
+	return rec.Code, res
+}
+
+func newDependency(name string, required bool, err error) Dependency {
+	return Dependency{
+		Name:     name,
+		Required: required,
+		Check: func(ctx context.Context) error {
+			return err
+		},
+	}
+}
+
+func TestNewReadyHandler(t *testing.T) {
+	testErr := errors.New("some test error")
+
+	t.Run("all dependencies up is ready", func(t *testing.T) {
+		handler := NewReadyHandler([]Dependency{newDependency("redis", true, nil), newDependency("api", false, nil)}, time.Second, make(chan struct{}))
+		code, res := serveReady(t, handler)
+		if code != http.StatusOK || res.Status != status_ready || len(res.Dependencies) != 2 {
+			t.Errorf("Unexpected result: %v, %+v", code, res)
+		}
+		if res.Dependencies["redis"].Status != status_up || !res.Dependencies["redis"].Required {
+			t.Errorf("Unexpected redis status: %+v", res.Dependencies["redis"])
+		}
+	})
+
+	t.Run("optional dependency down is reported, but ready", func(t *testing.T) {
+		handler := NewReadyHandler([]Dependency{newDependency("redis", true, nil), newDependency("api", false, testErr)}, time.Second, make(chan struct{}))
+		code, res := serveReady(t, handler)
+		if code != http.StatusOK || res.Status != status_ready {
+			t.Errorf("Unexpected result: %v, %+v", code, res)
+		}
+		if res.Dependencies["api"].Status != status_down || res.Dependencies["api"].Error != testErr.Error() {
+			t.Errorf("Unexpected api status: %+v", res.Dependencies["api"])
+		}
+	})
+
+	t.Run("required dependency down is not ready", func(t *testing.T) {
+		handler := NewReadyHandler([]Dependency{newDependency("redis", true, testErr)}, time.Second, make(chan struct{}))
+		code, res := serveReady(t, handler)
+		if code != http.StatusServiceUnavailable || res.Status != status_not_ready {
+			t.Errorf("Unexpected result: %v, %+v", code, res)
+		}
+	})
+
+	t.Run("hanging dependency is down after timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		hanging := Dependency{
+			Name:     "redis",
+			Required: true,
+			Check: func(ctx context.Context) error {
+				<-release
+				return nil
+			},
+		}
+		handler := NewReadyHandler([]Dependency{hanging}, 10*time.Millisecond, make(chan struct{}))
+		code, res := serveReady(t, handler)
+		if code != http.StatusServiceUnavailable || res.Dependencies["redis"].Error != context.DeadlineExceeded.Error() {
+			t.Errorf("Unexpected result: %v, %+v", code, res)
+		}
+	})
+
+	t.Run("shutting down is not ready", func(t *testing.T) {
+		stopping := make(chan struct{})
+		close(stopping)
+		handler := NewReadyHandler([]Dependency{newDependency("redis", true, nil)}, time.Second, stopping)
+		code, res := serveReady(t, handler)
+		if code != http.StatusServiceUnavailable || res.Status != status_shutting_down {
+			t.Errorf("Unexpected result: %v, %+v", code, res)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package handles
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logs"
+)
+
+//NewRecoveryHandler turns a panic into 500 response, if nothing was sent to client yet. It's an outer middleware,
+//so request id is taken from response headers, where it's put by logger middleware.
+func NewRecoveryHandler(loggerFactory LoggerFactory, m *HandlerMetrics, route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := newStatusRecorder(w)
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			//client has gone, there is nothing to answer, net/http handles it silently
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			m.Panics.Inc(route)
+			requestId := rec.Header().Get(consts.HEADER_REQUEST_ID)
+			logger := loggerFactory().With(
+				logs.NewField("request_id", requestId),
+				logs.NewField("method", r.Method),
+				logs.NewField("path", r.URL.Path),
+			)
+			logger.With(logs.NewField("stack", string(debug.Stack()))).Errorf("Panic occurred in handler: %v. Type: %T.", p, p)
+			if rec.status != 0 {
+				logger.Warningf("Response is already sent, status can't be changed.")
+				return
+			}
+			res, err := logic.NewErrorResponse(fmt.Errorf("panic: %v", p), requestId)
+			if err != nil {
+				logger.Errorf("Failed to create error response. Error: %v", err)
+				rec.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			err = res.Write(rec)
+			if err != nil {
+				logger.Errorf("Failed to write response. Error: %v", err)
+			}
+		}()
+		next(rec, r)
+	}
+}
@@ -0,0 +1,117 @@
+package handles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/metrics"
+)
+
+func TestNewRecoveryHandler(t *testing.T) {
+	route := "/v1/contact/{id}"
+
+	t.Run("no panic - response is passed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newMiddlewareFixture(ctrl)
+		m := NewHandlerMetrics(metrics.NewRegistry())
+		handle := NewRecoveryHandler(f.NewLogger.Create, m, route, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rec := httptest.NewRecorder()
+
+		handle(rec, httptest.NewRequest(http.MethodGet, f.Url, nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("Unexpected status code: %v", rec.Code)
+		}
+	})
+
+	t.Run("panic is answered with 500, logged with stack and counted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newMiddlewareFixture(ctrl)
+		registry := metrics.NewRegistry()
+		m := NewHandlerMetrics(registry)
+		handle := NewRecoveryHandler(f.NewLogger.Create, m, route, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(consts.HEADER_REQUEST_ID, f.RequestId)
+			panic("something went wrong")
+		})
+		rec := httptest.NewRecorder()
+
+		f.NewLogger.EXPECT().Create().Return(f.Logger).Times(1)
+		f.Logger.EXPECT().With(fieldsMatcher{"request_id": f.RequestId}, fieldsMatcher{"method": http.MethodGet}, fieldsMatcher{"path": "/"}).Return(f.RequestLogger).Times(1)
+		f.RequestLogger.EXPECT().With(gomock.Any()).Do(func(fields ...logs.Field) {
+			if len(fields) != 1 || fields[0].Key != "stack" || !strings.Contains(fmt.Sprint(fields[0].Value), "recovery_test.go") {
+				t.Errorf("Unexpected fields: %v", fields)
+			}
+		}).Return(f.AccessLogger).Times(1)
+		f.AccessLogger.EXPECT().Errorf(gomock.Any(), "something went wrong", "something went wrong").Times(1)
+
+		handle(rec, httptest.NewRequest(http.MethodGet, f.Url, nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Unexpected status code: %v", rec.Code)
+		}
+		body := map[string]string{}
+		err := json.Unmarshal(rec.Body.Bytes(), &body)
+		if err != nil || body["code"] != "internal" || body["request_id"] != f.RequestId {
+			t.Errorf("Unexpected body: %v", rec.Body.String())
+		}
+		buf := bytes.Buffer{}
+		_ = registry.Write(&buf)
+		if !strings.Contains(buf.String(), `http_panics_total{route="/v1/contact/{id}"} 1`) {
+			t.Errorf("Panic is not counted:\n%v", buf.String())
+		}
+	})
+
+	t.Run("status is not changed, if response is already sent", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newMiddlewareFixture(ctrl)
+		m := NewHandlerMetrics(metrics.NewRegistry())
+		handle := NewRecoveryHandler(f.NewLogger.Create, m, route, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			panic("something went wrong")
+		})
+		rec := httptest.NewRecorder()
+
+		f.NewLogger.EXPECT().Create().Return(f.Logger).Times(1)
+		f.Logger.EXPECT().With(gomock.Any(), gomock.Any(), gomock.Any()).Return(f.RequestLogger).Times(1)
+		f.RequestLogger.EXPECT().With(gomock.Any()).Return(f.AccessLogger).Times(1)
+		f.AccessLogger.EXPECT().Errorf(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+		f.RequestLogger.EXPECT().Warningf(gomock.Any()).Times(1)
+
+		handle(rec, httptest.NewRequest(http.MethodGet, f.Url, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+			t.Errorf("Unexpected response: %v, %v", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("aborted handler panics further", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newMiddlewareFixture(ctrl)
+		m := NewHandlerMetrics(metrics.NewRegistry())
+		handle := NewRecoveryHandler(f.NewLogger.Create, m, route, func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+		defer func() {
+			if recover() != http.ErrAbortHandler {
+				t.Errorf("Expected ErrAbortHandler to be re-thrown.")
+			}
+		}()
+		handle(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, f.Url, nil))
+	})
+}
@@ -0,0 +1,44 @@
+package handles
+
+import "net/http"
+
+//statusRecorder remembers status code and number of bytes, that were sent to client
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(data)
+	s.bytes += n
+	return n, err
+}
+
+//Status is 200, if handler hasn't written anything
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	rec, ok := w.(*statusRecorder)
+	if ok {
+		return rec
+	}
+	return &statusRecorder{
+		ResponseWriter: w,
+	}
+}
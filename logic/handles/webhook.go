@@ -0,0 +1,14 @@
+package handles
+
+import (
+	"net/http"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/sources"
+)
+
+//getData is expected to verify signature of webhook, sender is not authenticated otherwise
+func NewWebhookHandler(loggerFactory LoggerFactory, invalidate sources.CacheInvalidator, getData logic.RequestDataExtractor) http.HandlerFunc {
+	handler := newHttpHandler(getData, logicHandler(invalidate))
+	return newCheckAndSetLoggerMiddleware(loggerFactory, handler)
+}
@@ -0,0 +1,27 @@
+package handles
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/mocks/mock_handles"
+	"github.com/coldze/test/mocks/mock_logic"
+	"github.com/coldze/test/mocks/mock_sources"
+)
+
+func TestNewWebhookHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	loggerFactory := mock_handles.NewMockLoggerFactory(ctrl)
+	rWrap := mock_sources.NewMockRedisWrap(ctrl)
+	getData := mock_logic.NewMockRequestDataExtractor(ctrl)
+
+	invalidate := sources.NewCacheInvalidator(rWrap, "contact", logic.ParseContact)
+	if NewWebhookHandler(loggerFactory.Create, invalidate, getData.Extract) == nil {
+		t.Errorf("Webhook handler is nil")
+	}
+}
@@ -0,0 +1,142 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	default_key_field     = "contact_id"
+	default_key_separator = ":"
+	key_path_root         = "$"
+)
+
+var ErrKeyMissing = errors.New("key is missing")
+
+//keyStep is either a name of a field or an index in array
+type keyStep struct {
+	name  string
+	index int
+}
+
+type keyPath struct {
+	expression string
+	steps      []keyStep
+}
+
+//parseKeyPath understands expressions like contact_id, $.data.contact.id or items[0].id
+func parseKeyPath(expression string) (*keyPath, error) {
+	path := &keyPath{
+		expression: expression,
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(expression, key_path_root), ".")
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("key path '%v' is empty", expression)
+	}
+	for _, part := range strings.Split(rest, ".") {
+		name := part
+		indexes := ""
+		bracket := strings.Index(part, "[")
+		if bracket >= 0 {
+			name = part[:bracket]
+			indexes = part[bracket:]
+		}
+		if len(name) > 0 {
+			path.steps = append(path.steps, keyStep{name: name, index: -1})
+		} else if len(indexes) == 0 {
+			return nil, fmt.Errorf("key path '%v' has empty field name", expression)
+		}
+		for len(indexes) > 0 {
+			end := strings.Index(indexes, "]")
+			if indexes[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("key path '%v' has invalid index", expression)
+			}
+			index, err := strconv.Atoi(indexes[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("key path '%v' has invalid index '%v'", expression, indexes[1:end])
+			}
+			path.steps = append(path.steps, keyStep{index: index})
+			indexes = indexes[end+1:]
+		}
+	}
+	return path, nil
+}
+
+//value returns key part as a string, only strings and numbers can be a part of key
+func (p *keyPath) value(document interface{}) (string, error) {
+	current := document
+	for _, step := range p.steps {
+		if step.index < 0 {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%w: '%v' not found", ErrKeyMissing, p.expression)
+			}
+			current, ok = object[step.name]
+			if !ok {
+				return "", fmt.Errorf("%w: '%v' not found", ErrKeyMissing, p.expression)
+			}
+			continue
+		}
+		array, ok := current.([]interface{})
+		if !ok || step.index >= len(array) {
+			return "", fmt.Errorf("%w: '%v' not found", ErrKeyMissing, p.expression)
+		}
+		current = array[step.index]
+	}
+	switch typed := current.(type) {
+	case string:
+		if len(typed) == 0 {
+			return "", fmt.Errorf("%w: '%v' is empty", ErrKeyMissing, p.expression)
+		}
+		return typed, nil
+	case json.Number:
+		return typed.String(), nil
+	case nil:
+		return "", fmt.Errorf("%w: '%v' is null", ErrKeyMissing, p.expression)
+	}
+	return "", fmt.Errorf("'%v' must be a string or a number, got %T", p.expression, current)
+}
+
+//NewContactParser returns parser, that builds key from values of fields, found by expressions. Values of composite
+//key are joined with separator (":" by default). Parser fails, if any of the fields is missing or empty.
+func NewContactParser(expressions []string, separator string) (func([]byte) (Contact, error), error) {
+	if len(expressions) == 0 {
+		return nil, errors.New("at least one key path is required")
+	}
+	if len(separator) == 0 {
+		separator = default_key_separator
+	}
+	paths := make([]*keyPath, 0, len(expressions))
+	for _, expression := range expressions {
+		path, err := parseKeyPath(expression)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return func(data []byte) (Contact, error) {
+		var document interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		//numbers are kept as they are, big ids are not turned into 1e+21
+		decoder.UseNumber()
+		err := decoder.Decode(&document)
+		if err != nil {
+			return Contact{}, err
+		}
+		parts := make([]string, 0, len(paths))
+		for _, path := range paths {
+			part, err := path.value(document)
+			if err != nil {
+				return Contact{}, err
+			}
+			parts = append(parts, part)
+		}
+		return Contact{
+			ID: strings.Join(parts, separator),
+		}, nil
+	}, nil
+}
@@ -0,0 +1,86 @@
+package logic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewContactParser(t *testing.T) {
+	t.Run("keys are found by paths", func(t *testing.T) {
+		cases := []struct {
+			paths    []string
+			data     string
+			expected string
+		}{
+			{[]string{"contact_id"}, `{"contact_id": "test_id"}`, "test_id"},
+			{[]string{"$.data.contact.id"}, `{"data": {"contact": {"id": "nested"}}}`, "nested"},
+			{[]string{"items[1].id"}, `{"items": [{"id": "a"}, {"id": "b"}]}`, "b"},
+			{[]string{"[0]"}, `["first"]`, "first"},
+			{[]string{"matrix[1][0]"}, `{"matrix": [[1], [12345678901234567890]]}`, "12345678901234567890"},
+			{[]string{"tenant", "contact.id"}, `{"tenant": "acme", "contact": {"id": 42}}`, "acme:42"},
+		}
+		for _, c := range cases {
+			parse, err := NewContactParser(c.paths, "")
+			if err != nil {
+				t.Errorf("Paths %v. Unexpected error: %v", c.paths, err)
+				continue
+			}
+			contact, err := parse([]byte(c.data))
+			if err != nil || contact.ID != c.expected {
+				t.Errorf("Paths %v. Expected: %v. Got: %v, %v", c.paths, c.expected, contact.ID, err)
+			}
+		}
+	})
+
+	t.Run("separator is configurable", func(t *testing.T) {
+		parse, err := NewContactParser([]string{"a", "b"}, "/")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		contact, err := parse([]byte(`{"a": "x", "b": "y"}`))
+		if err != nil || contact.ID != "x/y" {
+			t.Errorf("Unexpected key: %v, %v", contact.ID, err)
+		}
+	})
+
+	t.Run("missing key is a failure", func(t *testing.T) {
+		parse, err := NewContactParser([]string{"tenant", "contact.id"}, "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, data := range []string{
+			`{"contact": {"id": "1"}}`,
+			`{"tenant": "acme"}`,
+			`{"tenant": "acme", "contact": []}`,
+			`{"tenant": "", "contact": {"id": "1"}}`,
+			`{"tenant": null, "contact": {"id": "1"}}`,
+		} {
+			_, err := parse([]byte(data))
+			if !errors.Is(err, ErrKeyMissing) {
+				t.Errorf("Data %v. Unexpected error: %v", data, err)
+			}
+		}
+	})
+
+	t.Run("key of unsupported type is a failure", func(t *testing.T) {
+		parse, err := NewContactParser([]string{"id"}, "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, data := range []string{`{"id": {}}`, `{"id": true}`, `INVALID JSON`} {
+			_, err := parse([]byte(data))
+			if err == nil || errors.Is(err, ErrKeyMissing) {
+				t.Errorf("Data %v. Unexpected error: %v", data, err)
+			}
+		}
+	})
+
+	t.Run("invalid paths are rejected", func(t *testing.T) {
+		for _, paths := range [][]string{nil, {""}, {"$"}, {"a..b"}, {"a[x]"}, {"a[-1]"}, {"a[1"}, {"a[1]b"}} {
+			_, err := NewContactParser(paths, "")
+			if err == nil {
+				t.Errorf("Expected error for paths: %v", paths)
+			}
+		}
+	})
+}
@@ -1,9 +1,11 @@
 package logic
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/coldze/test/consts"
+	"github.com/coldze/test/schema"
 )
 
 type Response interface {
@@ -37,6 +39,56 @@ func NewHttpResponse(data []byte, headers http.Header, code int) (Response, erro
 	}, nil
 }
 
+//headersResponse adds headers on top of another response, for example, to mark cached response as stale
+type headersResponse struct {
+	base    Response
+	headers http.Header
+}
+
+func (r *headersResponse) Write(w http.ResponseWriter) error {
+	for k, v := range r.headers {
+		w.Header()[k] = v
+	}
+	return r.base.Write(w)
+}
+
+func NewResponseWithHeaders(base Response, headers http.Header) Response {
+	return &headersResponse{
+		base:    base,
+		headers: headers,
+	}
+}
+
+func NewStaleResponse(base Response) Response {
+	headers := http.Header{}
+	headers.Set(consts.HEADER_CACHE_STATUS, consts.CACHE_STATUS_STALE)
+	return NewResponseWithHeaders(base, headers)
+}
+
+type errorBody struct {
+	Code       string             `json:"code"`
+	Message    string             `json:"message"`
+	RequestId  string             `json:"request_id,omitempty"`
+	Violations []schema.Violation `json:"violations,omitempty"`
+}
+
+//NewErrorResponse is used, when request failed: status and code are taken from error, internal details are not shown.
+func NewErrorResponse(err error, requestId string) (Response, error) {
+	kind := KindOf(err)
+	data, mErr := json.Marshal(&errorBody{
+		Code:       kind.Code(),
+		Message:    messageOf(err),
+		RequestId:  requestId,
+		Violations: violationsOf(err),
+	})
+	if mErr != nil {
+		return nil, mErr
+	}
+	headers := http.Header{}
+	headers.Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+	return NewHttpResponse(data, headers, StatusOf(err))
+}
+
 func NewJsonOkResponse(data []byte) (Response, error) {
 	headers := http.Header{}
 	headers.Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
@@ -2,9 +2,11 @@ package logic
 
 import (
 	"errors"
+	"github.com/coldze/test/consts"
 	"github.com/coldze/test/mocks"
 	"github.com/coldze/test/mocks/mock_logic"
 	"github.com/coldze/test/mocks/mock_std"
+	"github.com/coldze/test/schema"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	"net/http"
@@ -125,6 +127,72 @@ func TestNewHttpResponse(t *testing.T) {
 	})
 }
 
+func TestNewStaleResponse(t *testing.T) {
+	t.Run("stale header is added", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		base := mocks.NewMockResponse(ctrl)
+		r := NewStaleResponse(base)
+		rec := httptest.NewRecorder()
+		base.EXPECT().Write(rec).Return(nil).Times(1)
+		err := r.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Header().Get(consts.HEADER_CACHE_STATUS) != consts.CACHE_STATUS_STALE {
+			t.Errorf("Stale header is not set. Headers: %+v", rec.Header())
+		}
+	})
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	t.Run("typed error", func(t *testing.T) {
+		cause := errors.New("some details")
+		r, err := NewErrorResponse(NewError(ErrorUpstreamUnavailable, "test message", cause), "test-id")
+		mocks.CmpError(t, err, nil)
+		rec := httptest.NewRecorder()
+		err = r.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Unexpected status code: %v", rec.Code)
+		}
+		if rec.Header().Get(consts.HEADER_CONTENT_TYPE) != consts.MIME_APPLICATION_JSON {
+			t.Errorf("Unexpected headers: %+v", rec.Header())
+		}
+		if rec.Body.String() != "{\"code\":\"upstream_unavailable\",\"message\":\"test message\",\"request_id\":\"test-id\"}" {
+			t.Errorf("Unexpected body: %v", rec.Body.String())
+		}
+	})
+
+	t.Run("violations are added", func(t *testing.T) {
+		violations := []schema.Violation{{Field: "$.contact.Email", Message: "is required"}}
+		r, err := NewErrorResponse(NewValidationError(violations), "")
+		mocks.CmpError(t, err, nil)
+		rec := httptest.NewRecorder()
+		err = r.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Unexpected status code: %v", rec.Code)
+		}
+		if rec.Body.String() != "{\"code\":\"validation_failed\",\"message\":\"request body doesn't match schema\",\"violations\":[{\"field\":\"$.contact.Email\",\"message\":\"is required\"}]}" {
+			t.Errorf("Unexpected body: %v", rec.Body.String())
+		}
+	})
+
+	t.Run("untyped error is internal and hidden", func(t *testing.T) {
+		r, err := NewErrorResponse(errors.New("secret details"), "")
+		mocks.CmpError(t, err, nil)
+		rec := httptest.NewRecorder()
+		err = r.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Unexpected status code: %v", rec.Code)
+		}
+		if rec.Body.String() != "{\"code\":\"internal\",\"message\":\"internal error\"}" {
+			t.Errorf("Unexpected body: %v", rec.Body.String())
+		}
+	})
+}
+
 func TestNewJsonOkResponse(t *testing.T) {
 	t.Run("factory works", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
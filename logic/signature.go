@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_SIGNATURE_HEADER = "X-Webhook-Signature"
+	DEFAULT_TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+
+	signature_prefix            = "sha256="
+	default_signature_tolerance = 5 * time.Minute
+)
+
+//SignatureOptions describes how sender signs requests: signature is a hex HMAC-SHA256 of "<timestamp>.<body>" with
+//shared secret, timestamp is in unix seconds. Requests older (or newer) than Tolerance are rejected, so captured
+//request can't be replayed later.
+type SignatureOptions struct {
+	Secret          []byte
+	SignatureHeader string
+	TimestampHeader string
+	//zero - default (5 minutes)
+	Tolerance time.Duration
+}
+
+//Sign returns value of signature header for body sent at timestamp
+func (o *SignatureOptions) Sign(timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, o.Secret)
+	_, _ = mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	_, _ = mac.Write([]byte{'.'})
+	_, _ = mac.Write(body)
+	return signature_prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (o *SignatureOptions) signatureHeader() string {
+	if len(o.SignatureHeader) == 0 {
+		return DEFAULT_SIGNATURE_HEADER
+	}
+	return o.SignatureHeader
+}
+
+func (o *SignatureOptions) timestampHeader() string {
+	if len(o.TimestampHeader) == 0 {
+		return DEFAULT_TIMESTAMP_HEADER
+	}
+	return o.TimestampHeader
+}
+
+func (o *SignatureOptions) tolerance() time.Duration {
+	if o.Tolerance <= 0 {
+		return default_signature_tolerance
+	}
+	return o.Tolerance
+}
+
+func newUnauthorizedError(message string) error {
+	return NewError(ErrorUnauthorized, message, nil)
+}
+
+func (o *SignatureOptions) verify(r *http.Request, body []byte, now time.Time) error {
+	rawTimestamp := r.Header.Get(o.timestampHeader())
+	if len(rawTimestamp) == 0 {
+		return newUnauthorizedError(fmt.Sprintf("%v header is required", o.timestampHeader()))
+	}
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return newUnauthorizedError(fmt.Sprintf("%v header is not a unix timestamp", o.timestampHeader()))
+	}
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > o.tolerance() || -skew > o.tolerance() {
+		return newUnauthorizedError("request is expired")
+	}
+	signature := r.Header.Get(o.signatureHeader())
+	if len(signature) == 0 {
+		return newUnauthorizedError(fmt.Sprintf("%v header is required", o.signatureHeader()))
+	}
+	//prefix is optional, but hex digits are compared as is
+	if !strings.HasPrefix(signature, signature_prefix) {
+		signature = signature_prefix + signature
+	}
+	if !hmac.Equal([]byte(signature), []byte(o.Sign(timestamp, body))) {
+		return newUnauthorizedError("signature doesn't match")
+	}
+	return nil
+}
+
+//NewSignatureExtractor fails with 401, if body is not signed with the secret or signature is too old.
+//Body is read in full, so it should be limited by previous extractor.
+func NewSignatureExtractor(opts SignatureOptions, getData RequestDataExtractor) RequestDataExtractor {
+	return newSignatureExtractor(opts, time.Now, getData)
+}
+
+func newSignatureExtractor(opts SignatureOptions, now func() time.Time, getData RequestDataExtractor) RequestDataExtractor {
+	return func(r *http.Request) ([]byte, error) {
+		data, err := getData(r)
+		if err != nil {
+			return data, err
+		}
+		err = opts.verify(r, data, now())
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logic"
+)
+
+type signatureFixture struct {
+	Opts SignatureOptions
+	Now  time.Time
+	Body string
+}
+
+func newSignatureFixture() *signatureFixture {
+	return &signatureFixture{
+		Opts: SignatureOptions{
+			Secret: []byte("some test secret"),
+		},
+		Now:  time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC),
+		Body: `{"contact_id": "some-id"}`,
+	}
+}
+
+func (f *signatureFixture) extractor() RequestDataExtractor {
+	return newSignatureExtractor(f.Opts, func() time.Time {
+		return f.Now
+	}, GetRequestBodyData)
+}
+
+func (f *signatureFixture) request(timestamp time.Time, signature string) *http.Request {
+	r := newJsonRequest(f.Body, "")
+	r.Header.Set(DEFAULT_TIMESTAMP_HEADER, strconv.FormatInt(timestamp.Unix(), 10))
+	r.Header.Set(DEFAULT_SIGNATURE_HEADER, signature)
+	return r
+}
+
+func TestNewSignatureExtractor(t *testing.T) {
+
+	t.Run("signed body is returned", func(t *testing.T) {
+		f := newSignatureFixture()
+		signed := f.Now.Add(-time.Minute)
+		signature := f.Opts.Sign(signed.Unix(), []byte(f.Body))
+		for _, value := range []string{signature, strings.TrimPrefix(signature, signature_prefix)} {
+			data, err := f.extractor()(f.request(signed, value))
+			mocks.CmpError(t, err, nil)
+			if string(data) != f.Body {
+				t.Errorf("Unexpected data: %v", string(data))
+			}
+		}
+	})
+
+	t.Run("custom headers are used", func(t *testing.T) {
+		f := newSignatureFixture()
+		f.Opts.SignatureHeader = "X-Signature"
+		f.Opts.TimestampHeader = "X-Timestamp"
+		r := newJsonRequest(f.Body, "")
+		r.Header.Set("X-Timestamp", strconv.FormatInt(f.Now.Unix(), 10))
+		r.Header.Set("X-Signature", f.Opts.Sign(f.Now.Unix(), []byte(f.Body)))
+		_, err := f.extractor()(r)
+		mocks.CmpError(t, err, nil)
+	})
+
+	t.Run("wrong signature is unauthorized", func(t *testing.T) {
+		f := newSignatureFixture()
+		other := SignatureOptions{Secret: []byte("other secret")}
+		cases := map[string]*http.Request{
+			"other secret":     f.request(f.Now, other.Sign(f.Now.Unix(), []byte(f.Body))),
+			"other timestamp":  f.request(f.Now, f.Opts.Sign(f.Now.Unix()-1, []byte(f.Body))),
+			"other body":       f.request(f.Now, f.Opts.Sign(f.Now.Unix(), []byte("{}"))),
+			"empty signature":  f.request(f.Now, ""),
+			"not a hex digest": f.request(f.Now, "sha256=zzz"),
+		}
+		for name, r := range cases {
+			_, err := f.extractor()(r)
+			if KindOf(err) != ErrorUnauthorized {
+				t.Errorf("Case '%v'. Unexpected error: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("expired or missing timestamp is unauthorized", func(t *testing.T) {
+		f := newSignatureFixture()
+		for _, signed := range []time.Time{f.Now.Add(-6 * time.Minute), f.Now.Add(6 * time.Minute)} {
+			_, err := f.extractor()(f.request(signed, f.Opts.Sign(signed.Unix(), []byte(f.Body))))
+			if KindOf(err) != ErrorUnauthorized {
+				t.Errorf("Signed at %v. Unexpected error: %v", signed, err)
+			}
+		}
+		for _, value := range []string{"", "yesterday"} {
+			r := f.request(f.Now, f.Opts.Sign(f.Now.Unix(), []byte(f.Body)))
+			r.Header.Set(DEFAULT_TIMESTAMP_HEADER, value)
+			_, err := f.extractor()(r)
+			if KindOf(err) != ErrorUnauthorized {
+				t.Errorf("Timestamp '%v'. Unexpected error: %v", value, err)
+			}
+		}
+	})
+
+	t.Run("tolerance is configurable", func(t *testing.T) {
+		f := newSignatureFixture()
+		f.Opts.Tolerance = 10 * time.Second
+		signed := f.Now.Add(-time.Minute)
+		_, err := f.extractor()(f.request(signed, f.Opts.Sign(signed.Unix(), []byte(f.Body))))
+		if KindOf(err) != ErrorUnauthorized {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("error of extractor is returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expErr := errors.New("some test error")
+		extractor := mock_logic.NewMockRequestDataExtractor(ctrl)
+		getData := NewSignatureExtractor(SignatureOptions{}, extractor.Extract)
+		extractor.EXPECT().Extract(gomock.Any()).Return(nil, expErr).Times(1)
+		_, err := getData(newJsonRequest("{}", ""))
+		mocks.CmpError(t, err, expErr)
+	})
+}
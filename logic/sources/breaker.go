@@ -0,0 +1,202 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coldze/test/logs"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open, external API is unavailable")
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+//BreakerPolicy describes when circuit breaker opens:
+//- it opens, when ratio of failed calls within Window reaches FailureRatio (but only after MinRequests calls);
+//- it stays open for CoolDown, all calls fail fast;
+//- after that it lets HalfOpenRequests calls through: one success closes it, one failure opens it again.
+//FailureRatio <= 0 disables circuit breaker.
+type BreakerPolicy struct {
+	FailureRatio     float64
+	MinRequests      int
+	Window           time.Duration
+	CoolDown         time.Duration
+	HalfOpenRequests int
+}
+
+type BreakerStatus struct {
+	State    string     `json:"state"`
+	Requests int        `json:"requests"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+type CircuitBreaker struct {
+	lock   sync.Mutex
+	policy BreakerPolicy
+	logger logs.Logger
+	now    func() time.Time
+
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	if state == BreakerOpen {
+		b.logger.Warningf("Circuit breaker: %v -> %v. Requests: %v, failures: %v.", b.state, state, b.requests, b.failures)
+	} else {
+		b.logger.Infof("Circuit breaker: %v -> %v.", b.state, state)
+	}
+	b.state = state
+	b.requests = 0
+	b.failures = 0
+	b.probes = 0
+	b.windowStart = b.now()
+	if state == BreakerOpen {
+		b.openedAt = b.windowStart
+	}
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.policy.FailureRatio <= 0 {
+		return nil
+	}
+	now := b.now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.policy.CoolDown {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		fallthrough
+	case BreakerHalfOpen:
+		limit := b.policy.HalfOpenRequests
+		if limit < 1 {
+			limit = 1
+		}
+		if b.probes >= limit {
+			return ErrCircuitOpen
+		}
+		b.probes++
+		return nil
+	}
+	if now.Sub(b.windowStart) >= b.policy.Window {
+		b.windowStart = now
+		b.requests = 0
+		b.failures = 0
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) report(resp *http.Response, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.policy.FailureRatio <= 0 {
+		return
+	}
+	failed := isUpstreamFailure(resp, err)
+	switch b.state {
+	case BreakerOpen:
+		return
+	case BreakerHalfOpen:
+		//canceled probe says nothing about external API, its slot is given to next probe
+		if errors.Is(err, context.Canceled) {
+			b.probes--
+			return
+		}
+		if failed {
+			b.setState(BreakerOpen)
+		} else {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests < b.policy.MinRequests {
+		return
+	}
+	if float64(b.failures)/float64(b.requests) >= b.policy.FailureRatio {
+		b.setState(BreakerOpen)
+	}
+}
+
+//client going away is not a failure of external API
+func isUpstreamFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	if resp == nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+func (b *CircuitBreaker) Wrap(do HttpDo) HttpDo {
+	return func(r *http.Request) (*http.Response, error) {
+		err := b.allow()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := do(r)
+		b.report(resp, err)
+		return resp, err
+	}
+}
+
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	status := BreakerStatus{
+		State:    b.state.String(),
+		Requests: b.requests,
+		Failures: b.failures,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+func NewCircuitBreaker(policy BreakerPolicy, logger logs.Logger) *CircuitBreaker {
+	return &CircuitBreaker{
+		policy:      policy,
+		logger:      logger,
+		now:         time.Now,
+		state:       BreakerClosed,
+		windowStart: time.Now(),
+	}
+}
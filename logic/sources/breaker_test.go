@@ -0,0 +1,231 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+	"github.com/coldze/test/mocks/mock_std"
+)
+
+type breakerFixture struct {
+	Now     time.Time
+	Logger  *mock_logs.MockLogger
+	Do      *mock_std.MockHttpWrap
+	Request *http.Request
+	Policy  BreakerPolicy
+}
+
+func newBreakerFixture(ctrl *gomock.Controller) *breakerFixture {
+	req, err := http.NewRequest(http.MethodGet, "https://test.url.com/v1/api", nil)
+	if err != nil {
+		panic(err)
+	}
+	return &breakerFixture{
+		Now:     time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC),
+		Logger:  mock_logs.NewMockLogger(ctrl),
+		Do:      mock_std.NewMockHttpWrap(ctrl),
+		Request: req,
+		Policy: BreakerPolicy{
+			FailureRatio:     0.5,
+			MinRequests:      2,
+			Window:           time.Minute,
+			CoolDown:         10 * time.Second,
+			HalfOpenRequests: 1,
+		},
+	}
+}
+
+func newTestableCircuitBreaker(f *breakerFixture) *CircuitBreaker {
+	return &CircuitBreaker{
+		policy: f.Policy,
+		logger: f.Logger,
+		now: func() time.Time {
+			return f.Now
+		},
+		state:       BreakerClosed,
+		windowStart: f.Now,
+	}
+}
+
+func (f *breakerFixture) open(t *testing.T, do HttpDo) {
+	f.Do.EXPECT().Do(f.Request).Return(nil, context.DeadlineExceeded).Times(2)
+	f.Logger.EXPECT().Warningf(gomock.Any(), BreakerClosed, BreakerOpen, 2, 2).Times(1)
+	for i := 0; i < 2; i++ {
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, context.DeadlineExceeded)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	t.Run("failures below min requests don't open breaker", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+
+		f.Do.EXPECT().Do(f.Request).Return(nil, context.DeadlineExceeded).Times(1)
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, context.DeadlineExceeded)
+		if b.Status().State != BreakerClosed.String() {
+			t.Errorf("Unexpected state: %+v", b.Status())
+		}
+	})
+
+	t.Run("open breaker fails fast", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+		f.open(t, do)
+
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, ErrCircuitOpen)
+		status := b.Status()
+		if status.State != BreakerOpen.String() || status.OpenedAt == nil || !status.OpenedAt.Equal(f.Now) {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+	})
+
+	t.Run("successful probe closes breaker", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+		f.open(t, do)
+		f.Now = f.Now.Add(f.Policy.CoolDown)
+
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerOpen, BreakerHalfOpen).Times(1)
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerHalfOpen, BreakerClosed).Times(1)
+		f.Do.EXPECT().Do(f.Request).Return(&http.Response{StatusCode: http.StatusOK}, nil).Times(1)
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, nil)
+		if b.Status().State != BreakerClosed.String() {
+			t.Errorf("Unexpected state: %+v", b.Status())
+		}
+	})
+
+	t.Run("failed probe opens breaker again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+		f.open(t, do)
+		f.Now = f.Now.Add(f.Policy.CoolDown)
+
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerOpen, BreakerHalfOpen).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), BreakerHalfOpen, BreakerOpen, 0, 0).Times(1)
+		f.Do.EXPECT().Do(f.Request).Return(&http.Response{StatusCode: http.StatusBadGateway}, nil).Times(1)
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, nil)
+		_, err = do(f.Request)
+		mocks.CmpError(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("canceled probe keeps breaker half-open", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+		f.open(t, do)
+		f.Now = f.Now.Add(f.Policy.CoolDown)
+
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerOpen, BreakerHalfOpen).Times(1)
+		f.Do.EXPECT().Do(f.Request).Return(nil, context.Canceled).Times(1)
+		_, err := do(f.Request)
+		mocks.CmpError(t, err, context.Canceled)
+		if b.Status().State != BreakerHalfOpen.String() {
+			t.Errorf("Unexpected state: %+v", b.Status())
+		}
+
+		//slot of canceled probe is released
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerHalfOpen, BreakerClosed).Times(1)
+		f.Do.EXPECT().Do(f.Request).Return(&http.Response{StatusCode: http.StatusOK}, nil).Times(1)
+		_, err = do(f.Request)
+		mocks.CmpError(t, err, nil)
+		if b.Status().State != BreakerClosed.String() {
+			t.Errorf("Unexpected state: %+v", b.Status())
+		}
+	})
+
+	t.Run("half-open breaker limits probes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		f.Now = f.Now.Add(f.Policy.CoolDown)
+		b.state = BreakerOpen
+		b.openedAt = f.Now.Add(-f.Policy.CoolDown)
+
+		f.Logger.EXPECT().Infof(gomock.Any(), BreakerOpen, BreakerHalfOpen).Times(1)
+		mocks.CmpError(t, b.allow(), nil)
+		mocks.CmpError(t, b.allow(), ErrCircuitOpen)
+	})
+
+	t.Run("counters are reset with new window", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+
+		f.Do.EXPECT().Do(f.Request).Return(nil, context.DeadlineExceeded).Times(1)
+		f.Do.EXPECT().Do(f.Request).Return(&http.Response{StatusCode: http.StatusOK}, nil).Times(1)
+		_, _ = do(f.Request)
+		f.Now = f.Now.Add(f.Policy.Window)
+		_, _ = do(f.Request)
+		status := b.Status()
+		if status.Requests != 1 || status.Failures != 0 {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+	})
+
+	t.Run("disabled breaker never opens", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newBreakerFixture(ctrl)
+		f.Policy.FailureRatio = 0
+		b := newTestableCircuitBreaker(f)
+		do := b.Wrap(f.Do.Do)
+
+		f.Do.EXPECT().Do(f.Request).Return(nil, context.DeadlineExceeded).Times(5)
+		for i := 0; i < 5; i++ {
+			_, err := do(f.Request)
+			mocks.CmpError(t, err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	if isUpstreamFailure(nil, context.Canceled) {
+		t.Errorf("Cancelled request is not a failure")
+	}
+	if !isUpstreamFailure(nil, context.DeadlineExceeded) {
+		t.Errorf("Timeout is a failure")
+	}
+	if !isUpstreamFailure(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil) {
+		t.Errorf("5xx is a failure")
+	}
+	if isUpstreamFailure(&http.Response{StatusCode: http.StatusNotFound}, nil) {
+		t.Errorf("4xx is not a failure")
+	}
+}
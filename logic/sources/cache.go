@@ -1,11 +1,46 @@
 package sources
 
 import (
+	"time"
+
 	"github.com/coldze/test/logic"
 )
 
+//Get returns cached response and its age. Nil response means cache-miss.
+//Values are kept separately for every partition (see Partitioner). Value is inserted under the key, it's requested by,
+//so lookup never depends on how key is built from body of response.
 type CacheSource interface {
-	Get(key string) (logic.Response, error)
-	Insert(response logic.Response) error
-	Remove(response logic.Response) error
+	Get(partition string, key string) (logic.Response, time.Duration, error)
+	Insert(partition string, key string, response logic.Response) error
+	Remove(partition string, response logic.Response) error
+	RemoveKey(partition string, key string) error
+}
+
+//CachePolicy describes for how long cached value is fresh and for how long it can be used after that (see RFC 5861):
+//- StaleWhileRevalidate - stale value is returned immediately and refreshed in background;
+//- StaleIfError - stale value is returned, when external API fails.
+type CachePolicy struct {
+	Ttl                  time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+}
+
+func (p CachePolicy) isFresh(age time.Duration) bool {
+	return age <= p.Ttl
+}
+
+func (p CachePolicy) canRevalidate(age time.Duration) bool {
+	return age <= p.Ttl+p.StaleWhileRevalidate
+}
+
+func (p CachePolicy) canServeOnError(age time.Duration) bool {
+	return age <= p.Ttl+p.StaleIfError
+}
+
+//StoreTtl is a hard TTL - for how long value should be kept in cache.
+func (p CachePolicy) StoreTtl() time.Duration {
+	if p.StaleWhileRevalidate > p.StaleIfError {
+		return p.Ttl + p.StaleWhileRevalidate
+	}
+	return p.Ttl + p.StaleIfError
 }
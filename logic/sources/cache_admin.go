@@ -0,0 +1,320 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/utils"
+)
+
+const (
+	cache_format_entry = "entry"
+	cache_format_plain = "plain"
+
+	default_scan_count    = 100
+	default_warm_parallel = 4
+)
+
+//CacheAdmin gives operators access to values in cache by keys of storage, e.g. 'contact:<partition>:<id>'. Only keys
+//of configured resources are accessible. Methods have the same signature as methods of DataSource, so they are served
+//by the same handlers.
+type CacheAdmin interface {
+	Inspect(ctx context.Context, key []byte) (logic.Response, error)
+	Delete(ctx context.Context, key []byte) (logic.Response, error)
+	Purge(ctx context.Context, data []byte) (logic.Response, error)
+}
+
+//CacheWarmer fetches list of ids through data-source, so they get to cache of caller's partition
+type CacheWarmer func(ctx context.Context, data []byte) (logic.Response, error)
+
+type CacheEntryInfo struct {
+	Key        string      `json:"key"`
+	Format     string      `json:"format"`
+	TtlSeconds float64     `json:"ttl_seconds"`
+	Size       int         `json:"size"`
+	Status     int         `json:"status,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	FetchedAt  *time.Time  `json:"fetched_at,omitempty"`
+	AgeSeconds float64     `json:"age_seconds,omitempty"`
+	Body       string      `json:"body"`
+}
+
+//pattern is relative to prefix of resource, e.g. '*:12345' for '<resource>:*:12345'
+type purgeRequest struct {
+	Resource string `json:"resource"`
+	Pattern  string `json:"pattern"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+type purgeResult struct {
+	Pattern string `json:"pattern"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+	Matched int64  `json:"matched,omitempty"`
+	Deleted int64  `json:"deleted"`
+}
+
+type deleteResult struct {
+	Key     string `json:"key"`
+	Deleted int64  `json:"deleted"`
+}
+
+type warmRequest struct {
+	Ids []string `json:"ids"`
+}
+
+type warmResult struct {
+	Warmed int               `json:"warmed"`
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+func newJsonResponse(value interface{}) (logic.Response, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return logic.NewJsonOkResponse(data)
+}
+
+func parseJsonRequest(data []byte, value interface{}) error {
+	err := json.Unmarshal(data, value)
+	if err != nil {
+		return logic.NewError(logic.ErrorBadRequest, "invalid request body", err)
+	}
+	return nil
+}
+
+type redisCacheAdmin struct {
+	cache     RedisWrap
+	prefixes  map[string]string
+	scanCount int64
+	now       func() time.Time
+}
+
+//scope allows keys with prefix of a resource. If keys of some resource are not prefixed, any other key might be its
+//key, except keys of indexes. Data, that is not cache, is accessible only in this case.
+func (r *redisCacheAdmin) scope(key string) error {
+	notPrefixed := false
+	for _, prefix := range r.prefixes {
+		if len(prefix) == 0 {
+			notPrefixed = true
+			continue
+		}
+		if strings.HasPrefix(key, prefix+":") {
+			return nil
+		}
+	}
+	if notPrefixed {
+		_, err := valueKey("", key)
+		if err == nil {
+			return nil
+		}
+	}
+	return logic.NewError(logic.ErrorBadRequest, fmt.Sprintf("key '%s' is not a key of configured resources", key), nil)
+}
+
+func (r *redisCacheAdmin) Inspect(ctx context.Context, key []byte) (logic.Response, error) {
+	err := r.scope(string(key))
+	if err != nil {
+		return nil, err
+	}
+	rawData, remaining, err := r.cache.GetWithTtl(string(key))
+	if err == redis.Nil {
+		return nil, logic.NewError(logic.ErrorNotFound, fmt.Sprintf("key '%s' is not found in cache", key), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := rawData.(string)
+	if !ok {
+		return nil, fmt.Errorf("cached data is not of type string, it's type is: %T", rawData)
+	}
+	info := &CacheEntryInfo{
+		Key:        string(key),
+		Format:     cache_format_plain,
+		TtlSeconds: remaining.Seconds(),
+		Size:       len(data),
+		Body:       data,
+	}
+	//key without expiration
+	if remaining < 0 {
+		info.TtlSeconds = -1
+	}
+	if isCacheEntry(data) {
+		entry, err := decodeCacheEntry(data)
+		if err != nil {
+			return nil, err
+		}
+		info.Format = cache_format_entry
+		info.Status = entry.Status
+		info.Headers = entry.Headers
+		info.FetchedAt = &entry.FetchedAt
+		info.AgeSeconds = r.now().Sub(entry.FetchedAt).Seconds()
+		info.Body = string(entry.Body)
+	}
+	return newJsonResponse(info)
+}
+
+func (r *redisCacheAdmin) Delete(ctx context.Context, key []byte) (logic.Response, error) {
+	err := r.scope(string(key))
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := r.cache.DelKeys(string(key))
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, logic.NewError(logic.ErrorNotFound, fmt.Sprintf("key '%s' is not found in cache", key), nil)
+	}
+	return newJsonResponse(&deleteResult{
+		Key:     string(key),
+		Deleted: deleted,
+	})
+}
+
+//Purge removes keys of one resource, that match pattern. Pattern is always scoped by prefix of resource, so other
+//resources and data, that is not cache, are never removed. Dry run only counts keys.
+func (r *redisCacheAdmin) Purge(ctx context.Context, data []byte) (logic.Response, error) {
+	req := purgeRequest{}
+	err := parseJsonRequest(data, &req)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(req.Pattern)) == 0 {
+		return nil, logic.NewError(logic.ErrorBadRequest, "pattern is required", nil)
+	}
+	prefix, ok := r.prefixes[req.Resource]
+	if !ok {
+		return nil, logic.NewError(logic.ErrorBadRequest, fmt.Sprintf("unknown resource '%v'", req.Resource), nil)
+	}
+	if len(prefix) == 0 {
+		return nil, logic.NewError(logic.ErrorBadRequest, fmt.Sprintf("keys of resource '%v' are not prefixed, they can't be purged by pattern", req.Resource), nil)
+	}
+	pattern := partitionedKey(prefix, req.Pattern)
+	res := &purgeResult{
+		Pattern: pattern,
+		DryRun:  req.DryRun,
+	}
+	if req.DryRun {
+		res.Matched, err = scanPattern(ctx, r.cache, pattern, r.scanCount, nil)
+		if err != nil {
+			return nil, err
+		}
+		return newJsonResponse(res)
+	}
+	res.Deleted, err = deleteByPattern(ctx, r.cache, pattern, r.scanCount)
+	if err != nil {
+		return nil, err
+	}
+	utils.GetLogger(ctx).Infof("Purged %v keys by pattern '%v'.", res.Deleted, pattern)
+	return newJsonResponse(res)
+}
+
+//deleteByPattern removes keys batch by batch, so redis is never blocked. Keys, that are added meanwhile, might stay.
+func deleteByPattern(ctx context.Context, cache RedisWrap, pattern string, scanCount int64) (int64, error) {
+	deleted := int64(0)
+	_, err := scanPattern(ctx, cache, pattern, scanCount, func(keys []string) error {
+		n, err := cache.DelKeys(keys...)
+		if err != nil {
+			return fmt.Errorf("failed to delete after %v deleted keys: %w", deleted, err)
+		}
+		deleted += n
+		return nil
+	})
+	return deleted, err
+}
+
+//scanPattern passes keys to handle batch by batch and returns number of found keys. SCAN might return the same key
+//more than once, so number is an estimate.
+func scanPattern(ctx context.Context, cache RedisWrap, pattern string, scanCount int64, handle func(keys []string) error) (int64, error) {
+	total := int64(0)
+	cursor := uint64(0)
+	for {
+		if ctx.Err() != nil {
+			utils.GetLogger(ctx).Warningf("Scan of '%v' is interrupted after %v keys.", pattern, total)
+			return total, ctx.Err()
+		}
+		keys, next, err := cache.Scan(cursor, pattern, scanCount)
+		if err != nil {
+			return total, fmt.Errorf("failed to scan after %v keys: %w", total, err)
+		}
+		if len(keys) > 0 && handle != nil {
+			err = handle(keys)
+			if err != nil {
+				return total, err
+			}
+		}
+		total += int64(len(keys))
+		cursor = next
+		if cursor == 0 {
+			return total, nil
+		}
+	}
+}
+
+//prefixes - prefixes of cache keys by names of resources, empty prefix - keys of resource are not prefixed
+func NewRedisCacheAdmin(cache RedisWrap, prefixes map[string]string) CacheAdmin {
+	return &redisCacheAdmin{
+		cache:     cache,
+		prefixes:  prefixes,
+		scanCount: default_scan_count,
+		now:       time.Now,
+	}
+}
+
+//NewCacheWarmer fetches ids in parallel (not more than parallel at a time). Values, that are already cached, are not
+//refreshed. Failure of one id doesn't stop others, all failures are reported.
+func NewCacheWarmer(src DataSource, parallel int) CacheWarmer {
+	if parallel < 1 {
+		parallel = default_warm_parallel
+	}
+	return func(ctx context.Context, data []byte) (logic.Response, error) {
+		req := warmRequest{}
+		err := parseJsonRequest(data, &req)
+		if err != nil {
+			return nil, err
+		}
+		if len(req.Ids) == 0 {
+			return nil, logic.NewError(logic.ErrorBadRequest, "ids are required", nil)
+		}
+		res := &warmResult{
+			Failed: map[string]string{},
+		}
+		lock := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		slots := make(chan struct{}, parallel)
+		for _, id := range req.Ids {
+			//hanging fetch holds its slot, so cancelled warm stops without waiting for it
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				defer func() {
+					<-slots
+				}()
+				_, err := src.Get(ctx, []byte(id))
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					res.Failed[id] = err.Error()
+					return
+				}
+				res.Warmed++
+			}(id)
+		}
+		wg.Wait()
+		return newJsonResponse(res)
+	}
+}
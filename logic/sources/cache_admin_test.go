@@ -0,0 +1,422 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+	"github.com/coldze/test/mocks/mock_sources"
+	"github.com/coldze/test/utils"
+)
+
+type cacheAdminFixture struct {
+	Key        string
+	Data       string
+	Error      error
+	Now        time.Time
+	Ctx        context.Context
+	Logger     *mock_logs.MockLogger
+	RedisWrap  *mock_sources.MockRedisWrap
+	DataSource *mock_sources.MockDataSource
+}
+
+func newCacheAdminFixture(ctrl *gomock.Controller) *cacheAdminFixture {
+	logger := mock_logs.NewMockLogger(ctrl)
+	return &cacheAdminFixture{
+		Key:        "contact:partition:some-id",
+		Data:       `{"contact_id":"some-id"}`,
+		Error:      errors.New("some test error"),
+		Now:        time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC),
+		Ctx:        utils.SetLogger(context.Background(), logger),
+		Logger:     logger,
+		RedisWrap:  mock_sources.NewMockRedisWrap(ctrl),
+		DataSource: mock_sources.NewMockDataSource(ctrl),
+	}
+}
+
+func newTestableCacheAdmin(f *cacheAdminFixture) *redisCacheAdmin {
+	return &redisCacheAdmin{
+		cache: f.RedisWrap,
+		prefixes: map[string]string{
+			"contact": "contact",
+			"legacy":  "",
+		},
+		scanCount: 2,
+		now: func() time.Time {
+			return f.Now
+		},
+	}
+}
+
+func readJsonResponse(t *testing.T, res logic.Response, value interface{}) {
+	if res == nil {
+		t.Fatalf("Response is nil.")
+	}
+	rec := httptest.NewRecorder()
+	err := res.Write(rec)
+	if err != nil {
+		t.Fatalf("Failed to write response: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("Unexpected status: %v", rec.Code)
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), value)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+}
+
+func TestRedisCacheAdmin_Inspect(t *testing.T) {
+
+	t.Run("missing key is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Key).Return(nil, time.Duration(0), redis.Nil).Times(1)
+		_, err := a.Inspect(f.Ctx, []byte(f.Key))
+		if logic.KindOf(err) != logic.ErrorNotFound {
+			t.Errorf("Expected not found. Got: %v", err)
+		}
+	})
+
+	t.Run("redis error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Key).Return(nil, time.Duration(0), f.Error).Times(1)
+		_, err := a.Inspect(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("plain entry is returned as is", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Key).Return(f.Data, 5*time.Second, nil).Times(1)
+		res, err := a.Inspect(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		info := CacheEntryInfo{}
+		readJsonResponse(t, res, &info)
+		if info.Format != cache_format_plain || info.Body != f.Data || info.TtlSeconds != 5 || info.Size != len(f.Data) {
+			t.Errorf("Unexpected info: %+v", info)
+		}
+	})
+
+	t.Run("cache entry is decoded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+		entry, err := encodeCacheEntry(&cacheEntry{
+			Version:   cache_entry_version,
+			Status:    http.StatusOK,
+			Body:      []byte(f.Data),
+			FetchedAt: f.Now.Add(-3 * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Failed to encode entry: %v", err)
+		}
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Key).Return(string(entry), time.Duration(-1), nil).Times(1)
+		res, err := a.Inspect(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		info := CacheEntryInfo{}
+		readJsonResponse(t, res, &info)
+		if info.Format != cache_format_entry || info.Body != f.Data || info.TtlSeconds != -1 || info.AgeSeconds != 3 || info.Status != http.StatusOK {
+			t.Errorf("Unexpected info: %+v", info)
+		}
+	})
+}
+
+func TestRedisCacheAdmin_Delete(t *testing.T) {
+
+	t.Run("missing key is not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().DelKeys(f.Key).Return(int64(0), nil).Times(1)
+		_, err := a.Delete(f.Ctx, []byte(f.Key))
+		if logic.KindOf(err) != logic.ErrorNotFound {
+			t.Errorf("Expected not found. Got: %v", err)
+		}
+	})
+
+	t.Run("redis error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().DelKeys(f.Key).Return(int64(0), f.Error).Times(1)
+		_, err := a.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().DelKeys(f.Key).Return(int64(1), nil).Times(1)
+		res, err := a.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		result := deleteResult{}
+		readJsonResponse(t, res, &result)
+		if result.Deleted != 1 || result.Key != f.Key {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestRedisCacheAdmin_Scope(t *testing.T) {
+
+	t.Run("keys outside of resources are rejected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+		delete(a.prefixes, "legacy")
+
+		for _, key := range []string{"session:some-id", "contacts:partition:some-id", "contact", "idx:contact:some-id"} {
+			_, err := a.Inspect(f.Ctx, []byte(key))
+			if logic.KindOf(err) != logic.ErrorBadRequest {
+				t.Errorf("Key '%v'. Inspect is not rejected: %v", key, err)
+			}
+			_, err = a.Delete(f.Ctx, []byte(key))
+			if logic.KindOf(err) != logic.ErrorBadRequest {
+				t.Errorf("Key '%v'. Delete is not rejected: %v", key, err)
+			}
+		}
+	})
+
+	t.Run("indexes are rejected, if keys of a resource are not prefixed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		_, err := a.Delete(f.Ctx, []byte("idx:some-id"))
+		if logic.KindOf(err) != logic.ErrorBadRequest {
+			t.Errorf("Delete is not rejected: %v", err)
+		}
+		f.RedisWrap.EXPECT().DelKeys("partition:some-id").Return(int64(1), nil).Times(1)
+		_, err = a.Delete(f.Ctx, []byte("partition:some-id"))
+		mocks.CmpError(t, err, nil)
+	})
+}
+
+func TestRedisCacheAdmin_Purge(t *testing.T) {
+
+	t.Run("invalid body is a bad request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		cases := []string{
+			"not json",
+			`{"resource": "contact", "pattern": " "}`,
+			`{"pattern": "*"}`,
+			`{"resource": "unknown", "pattern": "*"}`,
+			`{"resource": "legacy", "pattern": "*"}`,
+		}
+		for _, body := range cases {
+			_, err := a.Purge(f.Ctx, []byte(body))
+			if logic.KindOf(err) != logic.ErrorBadRequest {
+				t.Errorf("Body '%v'. Expected bad request. Got: %v", body, err)
+			}
+		}
+	})
+
+	t.Run("keys are deleted batch by batch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+		pattern := "contact:*:some-id"
+
+		gomock.InOrder(
+			f.RedisWrap.EXPECT().Scan(uint64(0), pattern, int64(2)).Return([]string{"a", "b"}, uint64(7), nil).Times(1),
+			f.RedisWrap.EXPECT().DelKeys("a", "b").Return(int64(2), nil).Times(1),
+			f.RedisWrap.EXPECT().Scan(uint64(7), pattern, int64(2)).Return([]string{}, uint64(9), nil).Times(1),
+			f.RedisWrap.EXPECT().Scan(uint64(9), pattern, int64(2)).Return([]string{"c"}, uint64(0), nil).Times(1),
+			f.RedisWrap.EXPECT().DelKeys("c").Return(int64(1), nil).Times(1),
+		)
+		f.Logger.EXPECT().Infof(gomock.Any(), int64(3), pattern).Times(1)
+		res, err := a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*:some-id"}`))
+		mocks.CmpError(t, err, nil)
+		result := purgeResult{}
+		readJsonResponse(t, res, &result)
+		if result.Deleted != 3 || result.Pattern != pattern {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("pattern can't leave prefix of resource", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().Scan(uint64(0), "contact:*", int64(2)).Return([]string{}, uint64(0), nil).Times(1)
+		f.Logger.EXPECT().Infof(gomock.Any(), int64(0), "contact:*").Times(1)
+		_, err := a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*"}`))
+		mocks.CmpError(t, err, nil)
+	})
+
+	t.Run("dry run only counts keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		gomock.InOrder(
+			f.RedisWrap.EXPECT().Scan(uint64(0), "contact:*", int64(2)).Return([]string{"a", "b"}, uint64(7), nil).Times(1),
+			f.RedisWrap.EXPECT().Scan(uint64(7), "contact:*", int64(2)).Return([]string{"c"}, uint64(0), nil).Times(1),
+		)
+		res, err := a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*", "dry_run": true}`))
+		mocks.CmpError(t, err, nil)
+		result := purgeResult{}
+		readJsonResponse(t, res, &result)
+		if !result.DryRun || result.Matched != 3 || result.Deleted != 0 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("scan error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().Scan(uint64(0), "contact:*", int64(2)).Return(nil, uint64(0), f.Error).Times(2)
+		_, err := a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*"}`))
+		mocks.CmpError(t, err, f.Error)
+		_, err = a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*", "dry_run": true}`))
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("delete error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+
+		f.RedisWrap.EXPECT().Scan(uint64(0), "contact:*", int64(2)).Return([]string{"a"}, uint64(3), nil).Times(1)
+		f.RedisWrap.EXPECT().DelKeys("a").Return(int64(0), f.Error).Times(1)
+		_, err := a.Purge(f.Ctx, []byte(`{"resource": "contact", "pattern": "*"}`))
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("cancelled context stops purge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		a := newTestableCacheAdmin(f)
+		ctx, cancel := context.WithCancel(f.Ctx)
+		cancel()
+
+		f.Logger.EXPECT().Warningf(gomock.Any(), "contact:*", int64(0)).Times(1)
+		_, err := a.Purge(ctx, []byte(`{"resource": "contact", "pattern": "*"}`))
+		mocks.CmpError(t, err, context.Canceled)
+	})
+}
+
+func TestNewCacheWarmer(t *testing.T) {
+
+	t.Run("ids are required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		warm := NewCacheWarmer(f.DataSource, 2)
+
+		_, err := warm(f.Ctx, []byte(`{"ids": []}`))
+		if logic.KindOf(err) != logic.ErrorBadRequest {
+			t.Errorf("Expected bad request. Got: %v", err)
+		}
+	})
+
+	t.Run("all ids are fetched, failures are reported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		warm := NewCacheWarmer(f.DataSource, 2)
+
+		f.DataSource.EXPECT().Get(f.Ctx, []byte("1")).Return(&DummyResponse{}, nil).Times(1)
+		f.DataSource.EXPECT().Get(f.Ctx, []byte("2")).Return(nil, f.Error).Times(1)
+		f.DataSource.EXPECT().Get(f.Ctx, []byte("3")).Return(&DummyResponse{}, nil).Times(1)
+		res, err := warm(f.Ctx, []byte(`{"ids": ["1", "2", "3"]}`))
+		mocks.CmpError(t, err, nil)
+		result := warmResult{}
+		readJsonResponse(t, res, &result)
+		if result.Warmed != 2 || len(result.Failed) != 1 || result.Failed["2"] != f.Error.Error() {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("cancelled warm doesn't wait for slot", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		warm := NewCacheWarmer(f.DataSource, 1)
+		ctx, cancel := context.WithCancel(f.Ctx)
+		release := make(chan struct{})
+		defer close(release)
+
+		f.DataSource.EXPECT().Get(ctx, []byte("1")).DoAndReturn(func(ctx context.Context, data []byte) (logic.Response, error) {
+			cancel()
+			<-release
+			return nil, ctx.Err()
+		}).Times(1)
+		_, err := warm(ctx, []byte(`{"ids": ["1", "2"]}`))
+		mocks.CmpError(t, err, context.Canceled)
+	})
+}
+
+func TestNewRedisCacheAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	res := NewRedisCacheAdmin(mock_sources.NewMockRedisWrap(ctrl), map[string]string{})
+	if res == nil {
+		t.Errorf("Factory returns nil")
+	}
+}
@@ -0,0 +1,64 @@
+package sources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	cache_entry_version = 1
+	//JSON can't start with '#', thus entries in this format can't be confused with plain-body entries, stored by previous versions
+	cache_entry_prefix = "#cache-entry:"
+)
+
+type cacheEntry struct {
+	Version   int         `json:"version"`
+	Status    int         `json:"status"`
+	Headers   http.Header `json:"headers,omitempty"`
+	Body      []byte      `json:"body"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+func isCacheEntry(data string) bool {
+	return strings.HasPrefix(data, cache_entry_prefix)
+}
+
+func encodeCacheEntry(entry *cacheEntry) ([]byte, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(cache_entry_prefix), data...), nil
+}
+
+func decodeCacheEntry(data string) (*cacheEntry, error) {
+	if !isCacheEntry(data) {
+		return nil, errors.New("cached data is not a cache entry")
+	}
+	entry := &cacheEntry{}
+	err := json.Unmarshal([]byte(data[len(cache_entry_prefix):]), entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Version != cache_entry_version {
+		return nil, fmt.Errorf("unsupported cache entry version: %v", entry.Version)
+	}
+	return entry, nil
+}
+
+//filterHeaders keeps only allowed headers, everything else (hop-by-hop, cookies, etc) is not cached
+func filterHeaders(headers http.Header, allowed []string) http.Header {
+	res := http.Header{}
+	for _, name := range allowed {
+		values, ok := headers[http.CanonicalHeaderKey(name)]
+		if !ok {
+			continue
+		}
+		res[http.CanonicalHeaderKey(name)] = append([]string{}, values...)
+	}
+	return res
+}
@@ -0,0 +1,69 @@
+package sources
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/coldze/test/mocks"
+)
+
+func TestCacheEntry(t *testing.T) {
+
+	t.Run("encoded entry is decoded", func(t *testing.T) {
+		entry := &cacheEntry{
+			Version:   cache_entry_version,
+			Status:    http.StatusOK,
+			Headers:   http.Header{"Etag": []string{"123"}},
+			Body:      []byte("{\"contact_id\": \"1\"}"),
+			FetchedAt: time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC),
+		}
+		data, err := encodeCacheEntry(entry)
+		mocks.CmpError(t, err, nil)
+		if !isCacheEntry(string(data)) {
+			t.Errorf("Encoded data is not recognized as entry: %v", string(data))
+		}
+		res, err := decodeCacheEntry(string(data))
+		mocks.CmpError(t, err, nil)
+		if !cmp.Equal(res, entry) {
+			t.Errorf("Expected: %+v. Got: %+v", entry, res)
+		}
+	})
+
+	t.Run("plain body is not an entry", func(t *testing.T) {
+		data := "{\"contact_id\": \"1\"}"
+		if isCacheEntry(data) {
+			t.Errorf("Plain body is recognized as entry")
+		}
+		_, err := decodeCacheEntry(data)
+		if err == nil {
+			t.Errorf("Error is nil")
+		}
+	})
+
+	t.Run("unknown version is a failure", func(t *testing.T) {
+		data, err := encodeCacheEntry(&cacheEntry{Version: cache_entry_version + 1})
+		mocks.CmpError(t, err, nil)
+		_, err = decodeCacheEntry(string(data))
+		if err == nil {
+			t.Errorf("Error is nil")
+		}
+	})
+}
+
+func TestFilterHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Set-Cookie", "secret")
+	headers.Add("Etag", "1")
+	res := filterHeaders(headers, []string{"content-type", "ETag", "Last-Modified"})
+	expected := http.Header{
+		"Content-Type": []string{"application/json"},
+		"Etag":         []string{"1"},
+	}
+	if !cmp.Equal(res, expected) {
+		t.Errorf("Expected: %+v. Got: %+v", expected, res)
+	}
+}
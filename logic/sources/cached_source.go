@@ -2,33 +2,135 @@ package sources
 
 import (
 	"context"
+	"errors"
 
+	"github.com/coldze/test/consts"
 	"github.com/coldze/test/logic"
 	"github.com/coldze/test/utils"
 )
 
+type backgroundRunner func(task func())
+
 type cachedDataSource struct {
-	original DataSource
-	cache    CacheSource
+	original   DataSource
+	cache      CacheSource
+	flights    *flightGroup
+	policy     CachePolicy
+	partition  Partitioner
+	parse      DataParser
+	background backgroundRunner
+}
+
+func (c *cachedDataSource) fetch(ctx context.Context, partition string, key []byte) (logic.Response, error) {
+	res, err := c.original.Get(ctx, key)
+	if err != nil {
+		return res, err
+	}
+	err = c.cache.Insert(partition, string(key), res)
+	if err != nil {
+		logger := utils.GetLogger(ctx)
+		logger.Warningf("Error occurred while inserting data to cache. Error: %v", err)
+	}
+	return res, nil
+}
+
+//on cache-miss only one call per key goes to original data-source, concurrent callers wait for its result.
+//calls are coalesced only within the same partition, tenants never wait for each other's result.
+func (c *cachedDataSource) fetchOnce(ctx context.Context, partition string, key []byte) (logic.Response, error) {
+	return c.flights.Do(ctx, partitionedKey(partition, string(key)), func(ctx context.Context) (logic.Response, error) {
+		return c.fetch(ctx, partition, key)
+	})
+}
+
+func (c *cachedDataSource) revalidate(ctx context.Context, partition string, key []byte) {
+	ctx = utils.DetachContext(ctx)
+	c.background(func() {
+		_, err := c.fetchOnce(ctx, partition, key)
+		if err != nil {
+			logger := utils.GetLogger(ctx)
+			logger.Warningf("Failed to refresh stale value in background. Error: %v", err)
+		}
+	})
+}
+
+//canServeStaleOn is true, if external API failed or couldn't be reached: 5xx, network errors, timeouts and open
+//circuit breaker
+func canServeStaleOn(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	switch logic.KindOf(err) {
+	case logic.ErrorUpstreamFailed, logic.ErrorUpstreamUnavailable, logic.ErrorUpstreamTimeout:
+		return true
+	}
+	return false
 }
 
 func (c *cachedDataSource) Get(ctx context.Context, key []byte) (logic.Response, error) {
 	logger := utils.GetLogger(ctx)
-	res, err := c.cache.Get(string(key))
+	partition := c.partition(ctx)
+	cached, age, err := c.cache.Get(partition, string(key))
 	if err != nil {
 		logger.Warningf("Error occurred while getting data from cache. Error: %v", err)
-	} else if res != nil {
+		cached = nil
+	}
+	if cached == nil {
+		utils.SetCacheStatus(ctx, consts.CACHE_STATUS_MISS)
+		return c.fetchOnce(ctx, partition, key)
+	}
+	if c.policy.isFresh(age) {
+		utils.SetCacheStatus(ctx, consts.CACHE_STATUS_HIT)
+		return cached, nil
+	}
+	if c.policy.canRevalidate(age) {
+		utils.SetCacheStatus(ctx, consts.CACHE_STATUS_STALE)
+		c.revalidate(ctx, partition, key)
+		return logic.NewStaleResponse(cached), nil
+	}
+	utils.SetCacheStatus(ctx, consts.CACHE_STATUS_MISS)
+	res, err := c.fetchOnce(ctx, partition, key)
+	if err == nil {
 		return res, nil
 	}
-	res, err = c.original.Get(ctx, key)
-	if err != nil {
+	//answers of external API (e.g. deleted contact or revoked key) are never hidden by stale value
+	if !canServeStaleOn(err) {
+		return res, err
+	}
+	//while circuit breaker is open, anything we have in cache is better than nothing
+	if !c.policy.canServeOnError(age) && !errors.Is(err, ErrCircuitOpen) {
 		return res, err
 	}
-	err = c.cache.Insert(res)
+	logger.Warningf("Failed to get data, serving stale value. Error: %v", err)
+	utils.SetCacheStatus(ctx, consts.CACHE_STATUS_STALE)
+	return logic.NewStaleResponse(cached), nil
+}
+
+//removeKey is the same key, that httpDataSource sends to external API: from route, otherwise - from request body.
+//Response might be empty (e.g. 204 of update), so key is taken from it only if request has none.
+func (c *cachedDataSource) removeKey(ctx context.Context, data []byte) string {
+	key := utils.GetRouteKey(ctx)
+	if len(key) > 0 {
+		return key
+	}
+	contact, err := c.parse(data)
 	if err != nil {
-		logger.Warningf("Error occurred while inserting data to cache. Error: %v", err)
+		return ""
+	}
+	return contact.ID
+}
+
+func (c *cachedDataSource) invalidate(ctx context.Context, data []byte, res logic.Response) {
+	var err error
+	key := c.removeKey(ctx, data)
+	if len(key) > 0 {
+		err = c.cache.RemoveKey(c.partition(ctx), key)
+	} else {
+		err = c.cache.Remove(c.partition(ctx), res)
+	}
+	if err != nil {
+		logger := utils.GetLogger(ctx)
+		logger.Warningf("Failed to remove value from cache. Error: %v", err)
 	}
-	return res, nil
 }
 
 func (c *cachedDataSource) Create(ctx context.Context, data []byte) (logic.Response, error) {
@@ -36,7 +138,35 @@ func (c *cachedDataSource) Create(ctx context.Context, data []byte) (logic.Respo
 	if err != nil {
 		return res, err
 	}
-	err = c.cache.Remove(res)
+	c.invalidate(ctx, data, res)
+	return res, nil
+}
+
+func (c *cachedDataSource) Update(ctx context.Context, data []byte) (logic.Response, error) {
+	res, err := c.original.Update(ctx, data)
+	if err != nil {
+		return res, err
+	}
+	c.invalidate(ctx, data, res)
+	return res, nil
+}
+
+func (c *cachedDataSource) Patch(ctx context.Context, data []byte) (logic.Response, error) {
+	res, err := c.original.Patch(ctx, data)
+	if err != nil {
+		return res, err
+	}
+	c.invalidate(ctx, data, res)
+	return res, nil
+}
+
+//response of delete might be empty, that's why cached value is removed by requested key
+func (c *cachedDataSource) Delete(ctx context.Context, key []byte) (logic.Response, error) {
+	res, err := c.original.Delete(ctx, key)
+	if err != nil {
+		return res, err
+	}
+	err = c.cache.RemoveKey(c.partition(ctx), string(key))
 	if err != nil {
 		logger := utils.GetLogger(ctx)
 		logger.Warningf("Failed to remove value from cache. Error: %v", err)
@@ -44,13 +174,24 @@ func (c *cachedDataSource) Create(ctx context.Context, data []byte) (logic.Respo
 	return res, nil
 }
 
-func (c *cachedDataSource) Update(ctx context.Context, data []byte) (logic.Response, error) {
-	return c.Create(ctx, data)
+func (c *cachedDataSource) FlightStats() FlightStats {
+	return c.flights.Stats()
+}
+
+func runInBackground(task func()) {
+	go task()
 }
 
-func NewCachedDataSource(original DataSource, cache CacheSource) DataSource {
+//parse - gets key of a resource from request body, the same way as original data-source does.
+//observe is notified about calls to original data-source, that are shared by concurrent requests (nil - not observed)
+func NewCachedDataSource(original DataSource, cache CacheSource, policy CachePolicy, partition Partitioner, parse DataParser, observe FlightObserver) DataSource {
 	return &cachedDataSource{
-		original: original,
-		cache:    cache,
+		original:   original,
+		cache:      cache,
+		flights:    newFlightGroup(observe),
+		policy:     policy,
+		partition:  partition,
+		parse:      parse,
+		background: runInBackground,
 	}
 }
@@ -3,13 +3,17 @@ package sources
 import (
 	"context"
 	"errors"
+	"github.com/coldze/test/consts"
 	"github.com/coldze/test/logic"
 	"github.com/coldze/test/mocks"
 	"github.com/coldze/test/mocks/mock_logs"
 	"github.com/coldze/test/mocks/mock_sources"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/go-redis/redis"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 
@@ -29,24 +33,32 @@ func (d *DummyResponse) Write(w http.ResponseWriter) error {
 
 type cacheSourceFixture struct {
 	Key        string
+	Partition  string
 	Error      error
 	Ctx        context.Context
 	Logger     *mock_logs.MockLogger
 	Cache      *mock_sources.MockCacheSource
 	DataSource *mock_sources.MockDataSource
 	Response   logic.Response
+	Policy     CachePolicy
 }
 
 func newCacheSourceFixture(ctrl *gomock.Controller) *cacheSourceFixture {
 	ctx, logger := makeLoggerContext(ctrl)
 	return &cacheSourceFixture{
 		Key:        "some test key",
+		Partition:  "some test partition",
 		Error:      errors.New("Some test error"),
 		Ctx:        ctx,
 		Logger:     logger,
 		Cache:      mock_sources.NewMockCacheSource(ctrl),
 		DataSource: mock_sources.NewMockDataSource(ctrl),
 		Response:   &DummyResponse{},
+		Policy: CachePolicy{
+			Ttl:                  10 * time.Second,
+			StaleWhileRevalidate: 10 * time.Second,
+			StaleIfError:         20 * time.Second,
+		},
 	}
 }
 
@@ -54,6 +66,38 @@ func newTestableCachedDataSource(f *cacheSourceFixture) *cachedDataSource {
 	return &cachedDataSource{
 		original: f.DataSource,
 		cache:    f.Cache,
+		flights:  newFlightGroup(nil),
+		policy:   f.Policy,
+		partition: func(ctx context.Context) string {
+			return f.Partition
+		},
+		parse: logic.ParseContact,
+		background: func(task func()) {
+			task()
+		},
+	}
+}
+
+//on cache-miss original source is called with detached context, that is not the same object, but carries the same values
+type ctxValuesMatcher struct {
+	ctx context.Context
+}
+
+func (m *ctxValuesMatcher) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	if !ok {
+		return false
+	}
+	return utils.GetLogger(ctx) == utils.GetLogger(m.ctx)
+}
+
+func (m *ctxValuesMatcher) String() string {
+	return "context with the same logger"
+}
+
+func sameValues(ctx context.Context) gomock.Matcher {
+	return &ctxValuesMatcher{
+		ctx: ctx,
 	}
 }
 
@@ -67,9 +111,9 @@ func TestCachedDataSource_Get(t *testing.T) {
 		c := newTestableCachedDataSource(f)
 
 		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
-		f.Cache.EXPECT().Get(f.Key).Return(nil, f.Error).Times(1)
-		f.DataSource.EXPECT().Get(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Insert(f.Response).Return(nil).Times(1)
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), f.Error).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, f.Response).Return(nil).Times(1)
 		r, err := c.Get(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
 		if !cmp.Equal(r, f.Response) {
@@ -84,9 +128,9 @@ func TestCachedDataSource_Get(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.Cache.EXPECT().Get(f.Key).Return(nil, nil).Times(1)
-		f.DataSource.EXPECT().Get(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Insert(f.Response).Return(nil).Times(1)
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, f.Response).Return(nil).Times(1)
 		r, err := c.Get(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
 		if !cmp.Equal(r, f.Response) {
@@ -101,7 +145,7 @@ func TestCachedDataSource_Get(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.Cache.EXPECT().Get(f.Key).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, time.Duration(0), nil).Times(1)
 		r, err := c.Get(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
 		if !cmp.Equal(r, f.Response) {
@@ -109,6 +153,30 @@ func TestCachedDataSource_Get(t *testing.T) {
 		}
 	})
 
+	t.Run("cache status is reported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		f.Ctx = utils.WithCacheStatus(f.Ctx)
+		c := newTestableCachedDataSource(f)
+
+		gomock.InOrder(
+			f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, time.Duration(0), nil).Times(1),
+			f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), nil).Times(1),
+		)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, f.Response).Return(nil).Times(1)
+		_, _ = c.Get(f.Ctx, []byte(f.Key))
+		if utils.GetCacheStatus(f.Ctx) != consts.CACHE_STATUS_HIT {
+			t.Errorf("Unexpected cache status: %v", utils.GetCacheStatus(f.Ctx))
+		}
+		_, _ = c.Get(f.Ctx, []byte(f.Key))
+		if utils.GetCacheStatus(f.Ctx) != consts.CACHE_STATUS_MISS {
+			t.Errorf("Unexpected cache status: %v", utils.GetCacheStatus(f.Ctx))
+		}
+	})
+
 	t.Run("main source error leads to error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -116,8 +184,8 @@ func TestCachedDataSource_Get(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.Cache.EXPECT().Get(f.Key).Return(nil, nil).Times(1)
-		f.DataSource.EXPECT().Get(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(f.Response, f.Error).Times(1)
 		r, err := c.Get(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, f.Error)
 
@@ -133,9 +201,9 @@ func TestCachedDataSource_Get(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.Cache.EXPECT().Get(f.Key).Return(nil, nil).Times(1)
-		f.DataSource.EXPECT().Get(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Insert(f.Response).Return(f.Error).Times(1)
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, f.Response).Return(f.Error).Times(1)
 		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
 
 		r, err := c.Get(f.Ctx, []byte(f.Key))
@@ -147,6 +215,150 @@ func TestCachedDataSource_Get(t *testing.T) {
 	})
 }
 
+func expectStale(t *testing.T, r logic.Response) {
+	t.Helper()
+	if r == nil {
+		t.Fatalf("Expected stale response. Got nil.")
+	}
+	rec := httptest.NewRecorder()
+	err := r.Write(rec)
+	mocks.CmpError(t, err, nil)
+	if rec.Header().Get(consts.HEADER_CACHE_STATUS) != consts.CACHE_STATUS_STALE {
+		t.Errorf("Expected stale response. Headers: %+v", rec.Header())
+	}
+}
+
+func TestCachedDataSource_GetStale(t *testing.T) {
+
+	t.Run("stale value is returned and refreshed in background", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+		fresh := &DummyResponse{}
+
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+time.Second, nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(fresh, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, fresh).Return(nil).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		expectStale(t, r)
+	})
+
+	t.Run("background refresh error is logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+time.Second, nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(nil, f.Error).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		expectStale(t, r)
+	})
+
+	t.Run("value older than revalidate window is refreshed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+		fresh := &DummyResponse{}
+
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+f.Policy.StaleWhileRevalidate+time.Second, nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(fresh, nil).Times(1)
+		f.Cache.EXPECT().Insert(f.Partition, f.Key, fresh).Return(nil).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		if r != fresh {
+			t.Errorf("Expected fresh response.")
+		}
+	})
+
+	t.Run("stale value is returned on error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		failures := []error{
+			logic.NewError(logic.ErrorUpstreamFailed, "external API failed", f.Error),
+			logic.NewError(logic.ErrorUpstreamUnavailable, "external API is unavailable", f.Error),
+			logic.NewError(logic.ErrorUpstreamTimeout, "external API timed out", f.Error),
+			logic.NewUpstreamError("failed to call external API", f.Error),
+		}
+		for _, failure := range failures {
+			f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+f.Policy.StaleIfError, nil).Times(1)
+			f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(nil, failure).Times(1)
+			f.Logger.EXPECT().Warningf(gomock.Any(), failure).Times(1)
+			r, err := c.Get(f.Ctx, []byte(f.Key))
+			mocks.CmpError(t, err, nil)
+			expectStale(t, r)
+		}
+	})
+
+	t.Run("answers of external API are returned instead of stale value", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+		upstream := &DummyResponse{}
+
+		answers := map[string]error{
+			"404":      logic.NewError(logic.ErrorNotFound, "not found in external API", f.Error),
+			"403":      logic.NewErrorWithStatus(logic.ErrorUpstreamRejected, "request rejected by external API with status 403", f.Error, http.StatusForbidden),
+			"canceled": logic.NewUpstreamError("failed to call external API", context.Canceled),
+			"internal": f.Error,
+		}
+		for name, answer := range answers {
+			f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+f.Policy.StaleWhileRevalidate+time.Second, nil).Times(1)
+			f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(upstream, answer).Times(1)
+			r, err := c.Get(f.Ctx, []byte(f.Key))
+			mocks.CmpError(t, err, answer)
+			if r != upstream {
+				t.Errorf("Case '%v'. Expected response of external API.", name)
+			}
+		}
+	})
+
+	t.Run("any cached value is returned while circuit breaker is open", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+f.Policy.StaleIfError+time.Hour, nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(nil, ErrCircuitOpen).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), ErrCircuitOpen).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		expectStale(t, r)
+	})
+
+	t.Run("value older than error window is not returned on error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl+f.Policy.StaleIfError+time.Second, nil).Times(1)
+		f.DataSource.EXPECT().Get(sameValues(f.Ctx), []byte(f.Key)).Return(nil, f.Error).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+		if r != nil {
+			t.Errorf("Response should be nil.")
+		}
+	})
+}
+
 func TestCachedDataSource_Create(t *testing.T) {
 	t.Run("main source create error is a failure", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -172,7 +384,7 @@ func TestCachedDataSource_Create(t *testing.T) {
 		c := newTestableCachedDataSource(f)
 
 		f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Remove(f.Response).Return(f.Error).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(f.Error).Times(1)
 		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
 		r, err := c.Create(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
@@ -190,7 +402,7 @@ func TestCachedDataSource_Create(t *testing.T) {
 		c := newTestableCachedDataSource(f)
 
 		f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Remove(f.Response).Return(nil).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(nil).Times(1)
 		r, err := c.Create(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
 
@@ -201,14 +413,14 @@ func TestCachedDataSource_Create(t *testing.T) {
 }
 
 func TestCachedDataSource_Update(t *testing.T) {
-	t.Run("main source create error is a failure", func(t *testing.T) {
+	t.Run("main source update error is a failure", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
+		f.DataSource.EXPECT().Update(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, f.Error)
 
@@ -224,8 +436,8 @@ func TestCachedDataSource_Update(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Remove(f.Response).Return(f.Error).Times(1)
+		f.DataSource.EXPECT().Update(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(f.Error).Times(1)
 		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
@@ -242,8 +454,8 @@ func TestCachedDataSource_Update(t *testing.T) {
 		f := newCacheSourceFixture(ctrl)
 		c := newTestableCachedDataSource(f)
 
-		f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
-		f.Cache.EXPECT().Remove(f.Response).Return(nil).Times(1)
+		f.DataSource.EXPECT().Update(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(nil).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, nil)
 
@@ -251,6 +463,151 @@ func TestCachedDataSource_Update(t *testing.T) {
 			t.Errorf("Expected correct response.")
 		}
 	})
+	t.Run("value is removed by key from request, when update response is empty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		data := []byte(`{"contact_id":"42","name":"test"}`)
+		empty, err := logic.NewHttpResponse(nil, http.Header{}, http.StatusNoContent)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		f.DataSource.EXPECT().Update(f.Ctx, data).Return(empty, nil).Times(1)
+		f.Cache.EXPECT().RemoveKey(f.Partition, "42").Return(nil).Times(1)
+		r, err := c.Update(f.Ctx, data)
+		mocks.CmpError(t, err, nil)
+
+		if r != empty {
+			t.Errorf("Expected correct response.")
+		}
+	})
+}
+
+func TestCachedDataSource_Patch(t *testing.T) {
+	t.Run("main source patch error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Patch(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
+		r, err := c.Patch(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("cache remove error is logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Patch(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(f.Error).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
+		r, err := c.Patch(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Patch(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(nil).Times(1)
+		r, err := c.Patch(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("value is removed by key from route", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Patch(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().RemoveKey(f.Partition, f.Key).Return(nil).Times(1)
+		r, err := c.Patch(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+}
+
+func TestCachedDataSource_Delete(t *testing.T) {
+	t.Run("main source delete error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Delete(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("cache remove error is logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Delete(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+		f.Cache.EXPECT().RemoveKey(f.Partition, f.Key).Return(f.Error).Times(1)
+		f.Logger.EXPECT().Warningf(gomock.Any(), f.Error).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+
+		if !cmp.Equal(r, f.Response) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		c := newTestableCachedDataSource(f)
+
+		f.DataSource.EXPECT().Delete(f.Ctx, []byte(f.Key)).Return(nil, nil).Times(1)
+		f.Cache.EXPECT().RemoveKey(f.Partition, f.Key).Return(nil).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+
+		if r != nil {
+			t.Errorf("Response should be nil.")
+		}
+	})
 }
 
 func TestNewCachedDataSource(t *testing.T) {
@@ -260,8 +617,48 @@ func TestNewCachedDataSource(t *testing.T) {
 	dataSource := mock_sources.NewMockDataSource(ctrl)
 	cache := mock_sources.NewMockCacheSource(ctrl)
 
-	res := NewCachedDataSource(dataSource, cache)
+	res := NewCachedDataSource(dataSource, cache, CachePolicy{}, NoPartitioner, logic.ParseContact, nil)
 	if res == nil {
 		t.Errorf("Factory returns nil")
 	}
 }
+
+func TestCachedDataSource_CompositeKeyRoundTrip(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newCacheSourceFixture(ctrl)
+	parse, err := logic.NewContactParser([]string{"$.org", "$.id"}, ":")
+	mocks.CmpError(t, err, nil)
+	rWrap := mock_sources.NewMockRedisWrap(ctrl)
+	c := newTestableCachedDataSource(f)
+	c.cache = NewRedisCacheSource(rWrap, time.Minute, nil, parse, "contact")
+	key := "some-org:some-id"
+	stored := map[string]interface{}{}
+	upstream, err := logic.NewJsonOkResponse([]byte(`{"org": "some-org", "id": "some-id"}`))
+	mocks.CmpError(t, err, nil)
+
+	rWrap.EXPECT().GetWithTtl(f.Partition + ":" + key).DoAndReturn(func(key string) (interface{}, time.Duration, error) {
+		value, ok := stored[key]
+		if !ok {
+			return nil, 0, redis.Nil
+		}
+		return value, time.Minute, nil
+	}).Times(2)
+	rWrap.EXPECT().SetIndexed(f.Partition+":"+key, gomock.Any(), time.Minute, "idx:contact:"+key).DoAndReturn(func(key string, data interface{}, ttl time.Duration, index string) error {
+		//redis returns values as strings
+		stored[key] = string(data.([]byte))
+		return nil
+	}).Times(1)
+	f.DataSource.EXPECT().Get(gomock.Any(), []byte(key)).Return(upstream, nil).Times(1)
+
+	_, err = c.Get(f.Ctx, []byte(key))
+	mocks.CmpError(t, err, nil)
+	res, err := c.Get(f.Ctx, []byte(key))
+	mocks.CmpError(t, err, nil)
+	rec := httptest.NewRecorder()
+	mocks.CmpError(t, res.Write(rec), nil)
+	if rec.Body.String() != `{"org": "some-org", "id": "some-id"}` {
+		t.Errorf("Unexpected body: %v", rec.Body.String())
+	}
+}
@@ -7,11 +7,16 @@ import (
 )
 
 type httpDataBuilder struct {
-	data []byte
+	data    []byte
+	headers http.Header
+	code    int
 }
 
 func (h *httpDataBuilder) Header() http.Header {
-	return http.Header{}
+	if h.headers == nil {
+		h.headers = http.Header{}
+	}
+	return h.headers
 }
 
 func (h *httpDataBuilder) Write(data []byte) (int, error) {
@@ -20,6 +25,15 @@ func (h *httpDataBuilder) Write(data []byte) (int, error) {
 }
 
 func (h *httpDataBuilder) WriteHeader(code int) {
+	h.code = code
+}
+
+//StatusCode behaves the same way as http.ResponseWriter - if status was not written, it's 200
+func (h *httpDataBuilder) StatusCode() int {
+	if h.code == 0 {
+		return http.StatusOK
+	}
+	return h.code
 }
 
 func (h *httpDataBuilder) Build() ([]byte, error) {
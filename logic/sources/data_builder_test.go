@@ -2,13 +2,14 @@ package sources
 
 import (
 	"github.com/coldze/test/mocks"
+	"net/http"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 )
 
 func TestHttpDataBuilder_Header(t *testing.T) {
-	t.Run("returns new header every time", func(t *testing.T) {
+	t.Run("returns the same header every time", func(t *testing.T) {
 		builder := httpDataBuilder{}
 		h := builder.Header()
 		if len(h) > 0 {
@@ -16,14 +17,24 @@ func TestHttpDataBuilder_Header(t *testing.T) {
 		}
 		h.Set("123", "123")
 		h = builder.Header()
-		if len(h) > 0 {
-			t.Errorf("Headers len is: %v", len(h))
+		if h.Get("123") != "123" {
+			t.Errorf("Headers are not kept: %+v", h)
 		}
 	})
 
-	t.Run("write header no panic", func(t *testing.T) {
+	t.Run("status code is 200 by default", func(t *testing.T) {
+		builder := httpDataBuilder{}
+		if builder.StatusCode() != http.StatusOK {
+			t.Errorf("Unexpected status code: %v", builder.StatusCode())
+		}
+	})
+
+	t.Run("write header keeps status code", func(t *testing.T) {
 		builder := httpDataBuilder{}
 		builder.WriteHeader(123)
+		if builder.StatusCode() != 123 {
+			t.Errorf("Unexpected status code: %v", builder.StatusCode())
+		}
 	})
 
 	t.Run("write data succeeds and returns size of data", func(t *testing.T) {
@@ -10,4 +10,6 @@ type DataSource interface {
 	Get(ctx context.Context, data []byte) (logic.Response, error)
 	Create(ctx context.Context, data []byte) (logic.Response, error)
 	Update(ctx context.Context, data []byte) (logic.Response, error)
+	Patch(ctx context.Context, data []byte) (logic.Response, error)
+	Delete(ctx context.Context, key []byte) (logic.Response, error)
 }
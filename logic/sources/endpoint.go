@@ -0,0 +1,51 @@
+package sources
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	api_url_placeholder = "{api_url}"
+	key_placeholder     = "{contact_id}"
+	//the same as {contact_id}, reads better for resources, that are not contacts
+	generic_key_placeholder = "{key}"
+)
+
+//Endpoint describes how operation is mapped to external API: http-method and url template.
+//Template may contain {api_url} and {contact_id} (or {key}) placeholders.
+type Endpoint struct {
+	Method string
+	Url    string
+}
+
+type Endpoints struct {
+	Get    Endpoint
+	Create Endpoint
+	Update Endpoint
+	Patch  Endpoint
+	Delete Endpoint
+}
+
+func (e *Endpoint) needsKey() bool {
+	return strings.Contains(e.Url, key_placeholder) || strings.Contains(e.Url, generic_key_placeholder)
+}
+
+func (e *Endpoint) target(apiUrl string, key string) string {
+	escaped := url.PathEscape(key)
+	r := strings.NewReplacer(api_url_placeholder, apiUrl, key_placeholder, escaped, generic_key_placeholder, escaped)
+	return r.Replace(e.Url)
+}
+
+//DefaultEndpoints follow REST semantics of external API
+func DefaultEndpoints() Endpoints {
+	withKey := api_url_placeholder + "/" + key_placeholder
+	return Endpoints{
+		Get:    Endpoint{Method: http.MethodGet, Url: withKey},
+		Create: Endpoint{Method: http.MethodPost, Url: api_url_placeholder},
+		Update: Endpoint{Method: http.MethodPut, Url: withKey},
+		Patch:  Endpoint{Method: http.MethodPatch, Url: withKey},
+		Delete: Endpoint{Method: http.MethodDelete, Url: withKey},
+	}
+}
@@ -0,0 +1,30 @@
+package sources
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEndpoint_Target(t *testing.T) {
+	cases := []struct {
+		endpoint Endpoint
+		key      string
+		needsKey bool
+		expected string
+	}{
+		{Endpoint{Method: http.MethodPost, Url: "{api_url}"}, "", false, "https://test.url.com/v1/api"},
+		{Endpoint{Method: http.MethodPut, Url: "{api_url}/{contact_id}"}, "key", true, "https://test.url.com/v1/api/key"},
+		{Endpoint{Method: http.MethodPut, Url: "{api_url}/{contact_id}/fields"}, "a/b c", true, "https://test.url.com/v1/api/a%2Fb%20c/fields"},
+		{Endpoint{Method: http.MethodGet, Url: "https://other.url.com/{contact_id}"}, "key", true, "https://other.url.com/key"},
+		{Endpoint{Method: http.MethodDelete, Url: "{api_url}/lists/{key}"}, "acme:1", true, "https://test.url.com/v1/api/lists/acme:1"},
+	}
+	for _, c := range cases {
+		if c.endpoint.needsKey() != c.needsKey {
+			t.Errorf("Url '%v'. Expected needs key: %v", c.endpoint.Url, c.needsKey)
+		}
+		res := c.endpoint.target("https://test.url.com/v1/api", c.key)
+		if res != c.expected {
+			t.Errorf("Url '%v'. Expected: %v. Got: %v", c.endpoint.Url, c.expected, res)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package sources
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/utils"
+)
+
+type flightFunc func(ctx context.Context) (logic.Response, error)
+
+type FlightStats struct {
+	Flights   int64
+	Coalesced int64
+	Abandoned int64
+}
+
+type FlightStatsSource interface {
+	FlightStats() FlightStats
+}
+
+const (
+	FLIGHT_STARTED   = "started"
+	FLIGHT_COALESCED = "coalesced"
+	FLIGHT_ABANDONED = "abandoned"
+)
+
+//FlightObserver is notified about every flight event: FLIGHT_STARTED, FLIGHT_COALESCED or FLIGHT_ABANDONED
+type FlightObserver func(event string)
+
+func noFlightObserver(event string) {}
+
+type flightCall struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	res      logic.Response
+	err      error
+	waiters  int
+	joined   int
+	finished bool
+}
+
+//flightGroup makes sure, that only one call per key is in progress. Everyone else, who asks for the same key, waits for the result of that call.
+//Call runs with detached context, so it is not cancelled when the caller, who started it, goes away. It is cancelled only when all waiters are gone.
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[string]*flightCall
+
+	flights   int64
+	coalesced int64
+	abandoned int64
+	observe   FlightObserver
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn flightFunc) {
+	defer call.cancel()
+	res, err := fn(ctx)
+
+	g.lock.Lock()
+	call.res = res
+	call.err = err
+	call.finished = true
+	joined := call.joined
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.lock.Unlock()
+	close(call.done)
+
+	if joined > 0 {
+		logger := utils.GetLogger(ctx)
+		logger.Debugf("Coalesced %v call(s) for key '%v' into one.", joined, key)
+	}
+}
+
+func (g *flightGroup) leave(ctx context.Context, key string, call *flightCall) {
+	g.lock.Lock()
+	call.waiters--
+	abandoned := call.waiters == 0 && !call.finished
+	if abandoned && g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.lock.Unlock()
+	if !abandoned {
+		return
+	}
+	atomic.AddInt64(&g.abandoned, 1)
+	g.observe(FLIGHT_ABANDONED)
+	call.cancel()
+	logger := utils.GetLogger(ctx)
+	logger.Debugf("All waiters left, call for key '%v' is cancelled.", key)
+}
+
+func (g *flightGroup) Do(ctx context.Context, key string, fn flightFunc) (logic.Response, error) {
+	g.lock.Lock()
+	call, ok := g.calls[key]
+	if ok {
+		call.waiters++
+		call.joined++
+		g.lock.Unlock()
+		atomic.AddInt64(&g.coalesced, 1)
+		g.observe(FLIGHT_COALESCED)
+	} else {
+		callCtx, cancel := context.WithCancel(utils.DetachContext(ctx))
+		call = &flightCall{
+			done:    make(chan struct{}),
+			cancel:  cancel,
+			waiters: 1,
+		}
+		g.calls[key] = call
+		g.lock.Unlock()
+		atomic.AddInt64(&g.flights, 1)
+		g.observe(FLIGHT_STARTED)
+		go g.run(callCtx, key, call, fn)
+	}
+
+	select {
+	case <-call.done:
+		return call.res, call.err
+	case <-ctx.Done():
+		g.leave(ctx, key, call)
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) Stats() FlightStats {
+	return FlightStats{
+		Flights:   atomic.LoadInt64(&g.flights),
+		Coalesced: atomic.LoadInt64(&g.coalesced),
+		Abandoned: atomic.LoadInt64(&g.abandoned),
+	}
+}
+
+//nil observer - events are only counted in stats
+func newFlightGroup(observe FlightObserver) *flightGroup {
+	if observe == nil {
+		observe = noFlightObserver
+	}
+	return &flightGroup{
+		calls:   map[string]*flightCall{},
+		observe: observe,
+	}
+}
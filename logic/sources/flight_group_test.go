@@ -0,0 +1,145 @@
+package sources
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/mocks"
+)
+
+func TestFlightGroup_Do(t *testing.T) {
+
+	t.Run("single call returns result", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		g := newFlightGroup(nil)
+
+		r, err := g.Do(f.Ctx, f.Key, func(ctx context.Context) (logic.Response, error) {
+			return f.Response, f.Error
+		})
+		mocks.CmpError(t, err, f.Error)
+		if r != f.Response {
+			t.Errorf("Expected correct response.")
+		}
+		stats := g.Stats()
+		if stats.Flights != 1 || stats.Coalesced != 0 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("concurrent calls for the same key are coalesced", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		g := newFlightGroup(nil)
+		callers := 5
+		release := make(chan struct{})
+		started := make(chan struct{}, callers)
+		calls := 0
+		fn := func(ctx context.Context) (logic.Response, error) {
+			calls++
+			<-release
+			return f.Response, nil
+		}
+
+		f.Logger.EXPECT().Debugf(gomock.Any(), callers-1, f.Key).Times(1)
+		wg := sync.WaitGroup{}
+		wg.Add(callers)
+		for i := 0; i < callers; i++ {
+			go func() {
+				defer wg.Done()
+				started <- struct{}{}
+				r, err := g.Do(f.Ctx, f.Key, fn)
+				if err != nil || r != f.Response {
+					t.Errorf("Unexpected result: %v, %v", r, err)
+				}
+			}()
+		}
+		for i := 0; i < callers; i++ {
+			<-started
+		}
+		for g.Stats().Flights+g.Stats().Coalesced < int64(callers) {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		wg.Wait()
+		if calls != 1 {
+			t.Errorf("Expected exactly 1 call. Got: %v", calls)
+		}
+		stats := g.Stats()
+		if stats.Flights != 1 || stats.Coalesced != int64(callers-1) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("cancelled waiter leaves, others get result", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		g := newFlightGroup(nil)
+		release := make(chan struct{})
+		fn := func(ctx context.Context) (logic.Response, error) {
+			<-release
+			return f.Response, nil
+		}
+
+		f.Logger.EXPECT().Debugf(gomock.Any(), 1, f.Key).Times(1)
+		result := make(chan error, 1)
+		go func() {
+			_, err := g.Do(f.Ctx, f.Key, fn)
+			result <- err
+		}()
+		for g.Stats().Flights == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ctx, cancel := context.WithCancel(f.Ctx)
+		cancel()
+		r, err := g.Do(ctx, f.Key, fn)
+		mocks.CmpError(t, err, context.Canceled)
+		if r != nil {
+			t.Errorf("Response should be nil.")
+		}
+		close(release)
+		mocks.CmpError(t, <-result, nil)
+	})
+
+	t.Run("call is cancelled when all waiters left", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheSourceFixture(ctrl)
+		events := []string{}
+		g := newFlightGroup(func(event string) {
+			events = append(events, event)
+		})
+		ctx, cancel := context.WithCancel(f.Ctx)
+		cancelled := make(chan struct{})
+		fn := func(ctx context.Context) (logic.Response, error) {
+			cancel()
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+
+		f.Logger.EXPECT().Debugf(gomock.Any(), f.Key).Times(1)
+		_, err := g.Do(ctx, f.Key, fn)
+		mocks.CmpError(t, err, context.Canceled)
+		<-cancelled
+		stats := g.Stats()
+		if stats.Abandoned != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if len(events) != 2 || events[0] != FLIGHT_STARTED || events[1] != FLIGHT_ABANDONED {
+			t.Errorf("Unexpected events: %v", events)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package sources
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -12,10 +13,56 @@ import (
 type httpDataSource struct {
 	do             HttpDo
 	url            string
+	endpoints      Endpoints
+	parse          DataParser
 	createRequest  RequestFactory
 	createResponse logic.HttpResponseFactory
 }
 
+//key of a resource is taken from route, if it was there, otherwise - from body
+func (h *httpDataSource) key(ctx context.Context, data []byte) (string, error) {
+	key := utils.GetRouteKey(ctx)
+	if len(key) > 0 {
+		return key, nil
+	}
+	contact, err := h.parse(data)
+	if errors.Is(err, logic.ErrKeyMissing) {
+		return "", logic.NewError(logic.ErrorBadRequest, err.Error(), nil)
+	}
+	if err != nil {
+		return "", logic.NewError(logic.ErrorBadRequest, "failed to get key from body", err)
+	}
+	return contact.ID, nil
+}
+
+func (h *httpDataSource) send(ctx context.Context, endpoint Endpoint, data []byte) (logic.Response, error) {
+	if !endpoint.needsKey() {
+		return h.call(ctx, data, endpoint.target(h.url, ""), endpoint.Method)
+	}
+	key, err := h.key(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return h.call(ctx, data, endpoint.target(h.url, key), endpoint.Method)
+}
+
+//newStatusError maps non-2xx status of external API to error, client gets the same JSON error body for all of them.
+//Client errors (4xx) keep status of external API, failures of external API are not shown.
+func newStatusError(resp *http.Response) error {
+	cause := fmt.Errorf("response status code is not 2xx, code - %v, status - '%v'", resp.StatusCode, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return logic.NewError(logic.ErrorNotFound, "not found in external API", cause)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return logic.NewErrorWithStatus(logic.ErrorUpstreamRejected, fmt.Sprintf("request rejected by external API with status %v", resp.StatusCode), cause, resp.StatusCode)
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return logic.NewError(logic.ErrorUpstreamUnavailable, "external API is unavailable", cause)
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return logic.NewError(logic.ErrorUpstreamTimeout, "external API timed out", cause)
+	}
+	return logic.NewError(logic.ErrorUpstreamFailed, fmt.Sprintf("external API failed with status %v", resp.StatusCode), cause)
+}
+
 func (h *httpDataSource) call(ctx context.Context, data []byte, url string, method string) (logic.Response, error) {
 	req, err := h.createRequest(ctx, data, url, method)
 	if err != nil {
@@ -31,37 +78,52 @@ func (h *httpDataSource) call(ctx context.Context, data []byte, url string, meth
 			}
 		}()
 	}
+	if errors.Is(err, ErrCircuitOpen) {
+		//fail fast with a clear answer to the client
+		return nil, logic.NewError(logic.ErrorUpstreamUnavailable, "external API is unavailable", err)
+	}
 	if err != nil {
-		return nil, err
+		return nil, logic.NewUpstreamError("failed to call external API", err)
 	}
 	wrappedResp, err := h.createResponse(resp)
-	if err == nil && resp.StatusCode != 200 {
-		err = fmt.Errorf("response status code is not 200, code - %v, status - '%v'", resp.StatusCode, resp.Status)
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		err = newStatusError(resp)
 	}
 	return wrappedResp, err
 }
 
 func (h *httpDataSource) Get(ctx context.Context, key []byte) (logic.Response, error) {
-	return h.call(ctx, nil, h.url+"/"+string(key), http.MethodGet)
+	return h.call(ctx, nil, h.endpoints.Get.target(h.url, string(key)), h.endpoints.Get.Method)
 }
 
 func (h *httpDataSource) Create(ctx context.Context, data []byte) (logic.Response, error) {
-	return h.call(ctx, data, h.url, http.MethodPost)
+	return h.send(ctx, h.endpoints.Create, data)
 }
 
 func (h *httpDataSource) Update(ctx context.Context, data []byte) (logic.Response, error) {
-	return h.call(ctx, data, h.url, http.MethodPost)
+	return h.send(ctx, h.endpoints.Update, data)
+}
+
+func (h *httpDataSource) Patch(ctx context.Context, data []byte) (logic.Response, error) {
+	return h.send(ctx, h.endpoints.Patch, data)
+}
+
+func (h *httpDataSource) Delete(ctx context.Context, key []byte) (logic.Response, error) {
+	return h.call(ctx, nil, h.endpoints.Delete.target(h.url, string(key)), h.endpoints.Delete.Method)
 }
 
-func NewHttpDataSource(do HttpDo, url string) DataSource {
+//parse - gets key of a resource from request body, when it's not in route
+func NewHttpDataSource(do HttpDo, url string, endpoints Endpoints, parse DataParser) DataSource {
 	return &httpDataSource{
 		url:            url,
 		do:             do,
+		endpoints:      endpoints,
+		parse:          parse,
 		createRequest:  DefaultRequestFactory,
 		createResponse: logic.NewDefaultHttpResponseFactory(),
 	}
 }
 
 func NewDefaultHttpDataSource(url string) DataSource {
-	return NewHttpDataSource(http.DefaultClient.Do, url)
+	return NewHttpDataSource(http.DefaultClient.Do, url, DefaultEndpoints(), logic.ParseContact)
 }
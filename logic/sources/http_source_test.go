@@ -8,6 +8,7 @@ import (
 	"github.com/coldze/test/mocks/mock_logs"
 	"github.com/coldze/test/mocks/mock_sources"
 	"github.com/coldze/test/mocks/mock_std"
+	"github.com/coldze/test/utils"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -52,6 +53,8 @@ func newTestableHttpDataSource(f *httpDataSourceFixture) *httpDataSource {
 	return &httpDataSource{
 		url:            f.Url,
 		do:             f.Do.Do,
+		endpoints:      DefaultEndpoints(),
+		parse:          logic.ParseContact,
 		createResponse: f.CreateResponse.Create,
 		createRequest:  f.CreateRequest.Create,
 	}
@@ -153,6 +156,24 @@ func TestHttpDataSource_Get(t *testing.T) {
 		}
 	})
 
+	t.Run("open circuit breaker is upstream unavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+
+		req := httptest.NewRequest(http.MethodGet, f.Target, nil)
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, nil, f.Target, http.MethodGet).Return(req, nil).Times(1)
+		f.Do.EXPECT().Do(req).Return(nil, ErrCircuitOpen).Times(1)
+		r, err := c.Get(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, ErrCircuitOpen)
+		if r != nil || logic.KindOf(err) != logic.ErrorUpstreamUnavailable {
+			t.Errorf("Unexpected result: %v, %v", r, logic.KindOf(err))
+		}
+	})
+
 	t.Run("http call error is a failure (resp body is nil)", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -299,6 +320,51 @@ func TestHttpDataSource_Get(t *testing.T) {
 	})
 }
 
+func TestNewStatusError(t *testing.T) {
+	cases := map[int]logic.ErrorKind{
+		http.StatusBadRequest:          logic.ErrorUpstreamRejected,
+		http.StatusUnauthorized:        logic.ErrorUpstreamRejected,
+		http.StatusConflict:            logic.ErrorUpstreamRejected,
+		http.StatusNotFound:            logic.ErrorNotFound,
+		http.StatusTooManyRequests:     logic.ErrorUpstreamRejected,
+		http.StatusServiceUnavailable:  logic.ErrorUpstreamUnavailable,
+		http.StatusGatewayTimeout:      logic.ErrorUpstreamTimeout,
+		http.StatusInternalServerError: logic.ErrorUpstreamFailed,
+		http.StatusFound:               logic.ErrorUpstreamFailed,
+	}
+	for code, expected := range cases {
+		resp := &http.Response{StatusCode: code, Status: http.StatusText(code)}
+		res := logic.KindOf(newStatusError(resp))
+		if res != expected {
+			t.Errorf("Code %v. Expected: %v. Got: %v", code, expected, res)
+		}
+	}
+}
+
+func TestNewStatusError_Body(t *testing.T) {
+	cases := []struct {
+		code     int
+		status   int
+		expected string
+	}{
+		{http.StatusNotFound, http.StatusNotFound, `{"code":"not_found","message":"not found in external API","request_id":"test-id"}`},
+		{http.StatusConflict, http.StatusConflict, `{"code":"upstream_rejected","message":"request rejected by external API with status 409","request_id":"test-id"}`},
+		{http.StatusTooManyRequests, http.StatusTooManyRequests, `{"code":"upstream_rejected","message":"request rejected by external API with status 429","request_id":"test-id"}`},
+		{http.StatusInternalServerError, http.StatusBadGateway, `{"code":"upstream_error","message":"external API failed with status 500","request_id":"test-id"}`},
+	}
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: c.code, Status: http.StatusText(c.code)}
+		res, err := logic.NewErrorResponse(newStatusError(resp), "test-id")
+		mocks.CmpError(t, err, nil)
+		rec := httptest.NewRecorder()
+		err = res.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Code != c.status || rec.Body.String() != c.expected || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Code %v. Unexpected response: %v %v %v", c.code, rec.Code, rec.Header(), rec.Body.String())
+		}
+	}
+}
+
 func TestHttpDataSource_Create(t *testing.T) {
 	t.Run("create request error is a failure", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -546,9 +612,10 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(nil, f.Error).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(nil, f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, f.Error)
 		if !cmp.Equal(r, nil) {
@@ -561,10 +628,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		responseBody := mock_std.NewMockReadCloser(ctrl)
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusInternalServerError)
 		_, err := respRec.WriteString(f.Data)
@@ -575,7 +643,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = responseBody
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, f.Error).Times(1)
 		responseBody.EXPECT().Close().Return(nil).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
@@ -590,10 +658,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		responseBody := mock_std.NewMockReadCloser(ctrl)
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusInternalServerError)
 		_, err := respRec.WriteString(f.Data)
@@ -606,7 +675,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 
 		expErr := errors.New("Test")
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, expErr).Times(1)
 		responseBody.EXPECT().Close().Return(expErr).Times(1)
 		f.Logger.EXPECT().Warningf(gomock.Any(), expErr).Times(1)
@@ -622,11 +691,12 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(nil, f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, f.Error)
@@ -640,9 +710,10 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusInternalServerError)
 		_, err := respRec.WriteString(f.Data)
@@ -653,7 +724,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = nil
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
 		mocks.CmpError(t, err, f.Error)
@@ -667,10 +738,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		wrapResp := &DummyResponse{}
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusOK)
 		_, err := respRec.WriteString(f.Data)
@@ -681,7 +753,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = nil
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
 		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, nil).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
@@ -696,10 +768,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		var wrapResp logic.Response = nil
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusOK)
 		_, err := respRec.WriteString(f.Data)
@@ -710,7 +783,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = nil
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
 		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, nil).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
@@ -725,10 +798,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		wrapResp := &DummyResponse{}
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusInternalServerError)
 		_, err := respRec.WriteString(f.Data)
@@ -739,7 +813,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = nil
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
 		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, f.Error).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
@@ -754,10 +828,11 @@ func TestHttpDataSource_Update(t *testing.T) {
 		defer ctrl.Finish()
 
 		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
 		c := newTestableHttpDataSource(f)
 
 		wrapResp := &DummyResponse{}
-		req := httptest.NewRequest(http.MethodPost, f.Target, nil)
+		req := httptest.NewRequest(http.MethodPut, f.Target, nil)
 		respRec := httptest.NewRecorder()
 		respRec.WriteHeader(http.StatusInternalServerError)
 		_, err := respRec.WriteString(f.Data)
@@ -768,7 +843,7 @@ func TestHttpDataSource_Update(t *testing.T) {
 		resp := respRec.Result()
 		resp.Body = nil
 
-		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Url, http.MethodPost).Return(req, nil).Times(1)
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Key), f.Target, http.MethodPut).Return(req, nil).Times(1)
 		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
 		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, nil).Times(1)
 		r, err := c.Update(f.Ctx, []byte(f.Key))
@@ -781,12 +856,136 @@ func TestHttpDataSource_Update(t *testing.T) {
 	})
 }
 
+func TestHttpDataSource_Patch(t *testing.T) {
+	t.Run("contact id is taken from body", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+		data := []byte(`{"contact_id":"` + f.Key + `"}`)
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, data, f.Target, http.MethodPatch).Return(nil, f.Error).Times(1)
+		_, err := c.Patch(f.Ctx, data)
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("contact id from route has priority", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		f.Ctx = utils.SetRouteKey(f.Ctx, f.Key)
+		c := newTestableHttpDataSource(f)
+		data := []byte(`{"contact_id":"other_key"}`)
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, data, f.Target, http.MethodPatch).Return(nil, f.Error).Times(1)
+		_, err := c.Patch(f.Ctx, data)
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("missing contact id is a bad request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+
+		for _, data := range []string{`{"contact":{}}`, f.Data} {
+			_, err := c.Patch(f.Ctx, []byte(data))
+			if err == nil || logic.KindOf(err) != logic.ErrorBadRequest {
+				t.Errorf("Expected bad request for body: %v. Got: %v", data, err)
+			}
+		}
+	})
+
+	t.Run("endpoint without key doesn't need contact id", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+		c.endpoints.Patch = Endpoint{Method: http.MethodPost, Url: "{api_url}/partial"}
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, []byte(f.Data), f.Url+"/partial", http.MethodPost).Return(nil, f.Error).Times(1)
+		_, err := c.Patch(f.Ctx, []byte(f.Data))
+		mocks.CmpError(t, err, f.Error)
+	})
+}
+
+func TestHttpDataSource_Delete(t *testing.T) {
+	t.Run("create request error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, nil, f.Target, http.MethodDelete).Return(nil, f.Error).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, f.Error)
+		if !cmp.Equal(r, nil) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("http no content creates response", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+
+		wrapResp := &DummyResponse{}
+		req := httptest.NewRequest(http.MethodDelete, f.Target, nil)
+		respRec := httptest.NewRecorder()
+		respRec.WriteHeader(http.StatusNoContent)
+		resp := respRec.Result()
+		resp.Body = nil
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, nil, f.Target, http.MethodDelete).Return(req, nil).Times(1)
+		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
+		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, nil).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		mocks.CmpError(t, err, nil)
+		if !cmp.Equal(r, wrapResp) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("http not found returns error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newHttpDataSourceFixture(ctrl)
+		c := newTestableHttpDataSource(f)
+
+		wrapResp := &DummyResponse{}
+		req := httptest.NewRequest(http.MethodDelete, f.Target, nil)
+		respRec := httptest.NewRecorder()
+		respRec.WriteHeader(http.StatusNotFound)
+		resp := respRec.Result()
+		resp.Body = nil
+
+		f.CreateRequest.EXPECT().Create(f.Ctx, nil, f.Target, http.MethodDelete).Return(req, nil).Times(1)
+		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
+		f.CreateResponse.EXPECT().Create(resp).Return(wrapResp, nil).Times(1)
+		r, err := c.Delete(f.Ctx, []byte(f.Key))
+		if err == nil {
+			t.Errorf("Failed. Code: %v. Error: %v", resp.StatusCode, err)
+		}
+		if !cmp.Equal(r, wrapResp) {
+			t.Errorf("Expected correct response.")
+		}
+	})
+}
+
 func TestNewHttpDataSource(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	httpWrap := mock_std.NewMockHttpWrap(ctrl)
-	dataSource := NewHttpDataSource(httpWrap.Do, "test_url")
+	dataSource := NewHttpDataSource(httpWrap.Do, "test_url", DefaultEndpoints(), logic.ParseContact)
 	if dataSource == nil {
 		t.Errorf("Factory returned nil")
 	}
@@ -0,0 +1,170 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/metrics"
+)
+
+const (
+	result_ok    = "ok"
+	result_error = "error"
+	result_hit   = "hit"
+	result_miss  = "miss"
+
+	status_error        = "error"
+	status_circuit_open = "circuit_open"
+)
+
+//SourceMetrics are shared by all instrumented data-sources, caches and http-clients, they're labeled by resource.
+type SourceMetrics struct {
+	Operations *metrics.Histogram
+	Cache      *metrics.Counter
+	Upstream   *metrics.Histogram
+	Flights    *metrics.Counter
+}
+
+func NewSourceMetrics(registry *metrics.Registry) *SourceMetrics {
+	return &SourceMetrics{
+		Operations: registry.NewHistogram("data_source_operation_duration_seconds", "Duration of data-source operations, including cache.", metrics.DefaultBuckets, "resource", "operation", "result"),
+		Cache:      registry.NewCounter("cache_operations_total", "Number of cache operations.", "resource", "operation", "result"),
+		Upstream:   registry.NewHistogram("upstream_request_duration_seconds", "Duration of calls to external API.", metrics.DefaultBuckets, "resource", "method", "status"),
+		Flights:    registry.NewCounter("data_source_flights_total", "Number of calls to external API, started for cache misses, and requests, that joined or abandoned them.", "resource", "result"),
+	}
+}
+
+//NewFlightObserver counts flights of resource, result is "started", "coalesced" or "abandoned"
+func NewFlightObserver(m *SourceMetrics, resource string) FlightObserver {
+	return func(event string) {
+		m.Flights.Inc(resource, event)
+	}
+}
+
+func resultOf(err error) string {
+	if err != nil {
+		return result_error
+	}
+	return result_ok
+}
+
+type instrumentedDataSource struct {
+	original DataSource
+	metrics  *SourceMetrics
+	resource string
+}
+
+func (i *instrumentedDataSource) observe(operation string, start time.Time, err error) {
+	i.metrics.Operations.Observe(time.Since(start).Seconds(), i.resource, operation, resultOf(err))
+}
+
+func (i *instrumentedDataSource) Get(ctx context.Context, key []byte) (logic.Response, error) {
+	start := time.Now()
+	res, err := i.original.Get(ctx, key)
+	i.observe("get", start, err)
+	return res, err
+}
+
+func (i *instrumentedDataSource) Create(ctx context.Context, data []byte) (logic.Response, error) {
+	start := time.Now()
+	res, err := i.original.Create(ctx, data)
+	i.observe("create", start, err)
+	return res, err
+}
+
+func (i *instrumentedDataSource) Update(ctx context.Context, data []byte) (logic.Response, error) {
+	start := time.Now()
+	res, err := i.original.Update(ctx, data)
+	i.observe("update", start, err)
+	return res, err
+}
+
+func (i *instrumentedDataSource) Patch(ctx context.Context, data []byte) (logic.Response, error) {
+	start := time.Now()
+	res, err := i.original.Patch(ctx, data)
+	i.observe("patch", start, err)
+	return res, err
+}
+
+func (i *instrumentedDataSource) Delete(ctx context.Context, key []byte) (logic.Response, error) {
+	start := time.Now()
+	res, err := i.original.Delete(ctx, key)
+	i.observe("delete", start, err)
+	return res, err
+}
+
+func NewInstrumentedDataSource(original DataSource, m *SourceMetrics, resource string) DataSource {
+	return &instrumentedDataSource{
+		original: original,
+		metrics:  m,
+		resource: resource,
+	}
+}
+
+type instrumentedCacheSource struct {
+	cache    CacheSource
+	metrics  *SourceMetrics
+	resource string
+}
+
+func (i *instrumentedCacheSource) Get(partition string, key string) (logic.Response, time.Duration, error) {
+	res, age, err := i.cache.Get(partition, key)
+	result := result_hit
+	if err != nil {
+		result = result_error
+	} else if res == nil {
+		result = result_miss
+	}
+	i.metrics.Cache.Inc(i.resource, "get", result)
+	return res, age, err
+}
+
+func (i *instrumentedCacheSource) Insert(partition string, key string, response logic.Response) error {
+	err := i.cache.Insert(partition, key, response)
+	i.metrics.Cache.Inc(i.resource, "insert", resultOf(err))
+	return err
+}
+
+func (i *instrumentedCacheSource) Remove(partition string, response logic.Response) error {
+	err := i.cache.Remove(partition, response)
+	i.metrics.Cache.Inc(i.resource, "remove", resultOf(err))
+	return err
+}
+
+func (i *instrumentedCacheSource) RemoveKey(partition string, key string) error {
+	err := i.cache.RemoveKey(partition, key)
+	i.metrics.Cache.Inc(i.resource, "remove", resultOf(err))
+	return err
+}
+
+func NewInstrumentedCacheSource(cache CacheSource, m *SourceMetrics, resource string) CacheSource {
+	return &instrumentedCacheSource{
+		cache:    cache,
+		metrics:  m,
+		resource: resource,
+	}
+}
+
+func upstreamStatus(resp *http.Response, err error) string {
+	if errors.Is(err, ErrCircuitOpen) {
+		return status_circuit_open
+	}
+	if err != nil || resp == nil {
+		return status_error
+	}
+	return strconv.Itoa(resp.StatusCode)
+}
+
+//NewInstrumentedHttpDo measures calls to external API, status is a response code, "error" or "circuit_open"
+func NewInstrumentedHttpDo(do HttpDo, m *SourceMetrics, resource string) HttpDo {
+	return func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := do(r)
+		m.Upstream.Observe(time.Since(start).Seconds(), resource, r.Method, upstreamStatus(resp, err))
+		return resp, err
+	}
+}
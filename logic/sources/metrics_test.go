@@ -0,0 +1,144 @@
+package sources
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/metrics"
+	"github.com/coldze/test/mocks"
+)
+
+func expectMetrics(t *testing.T, registry *metrics.Registry, lines ...string) {
+	buf := bytes.Buffer{}
+	err := registry.Write(&buf)
+	if err != nil {
+		t.Errorf("Failed to write metrics: %v", err)
+	}
+	for _, line := range lines {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected line '%v' in metrics:\n%v", line, buf.String())
+		}
+	}
+}
+
+func TestNewFlightObserver(t *testing.T) {
+	registry := metrics.NewRegistry()
+	observe := NewFlightObserver(NewSourceMetrics(registry), "contact")
+	for _, event := range []string{FLIGHT_STARTED, FLIGHT_COALESCED, FLIGHT_COALESCED, FLIGHT_ABANDONED} {
+		observe(event)
+	}
+	expectMetrics(t, registry,
+		`data_source_flights_total{resource="contact",result="started"} 1`,
+		`data_source_flights_total{resource="contact",result="coalesced"} 2`,
+		`data_source_flights_total{resource="contact",result="abandoned"} 1`,
+	)
+}
+
+func TestInstrumentedDataSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newCacheSourceFixture(ctrl)
+	registry := metrics.NewRegistry()
+	s := NewInstrumentedDataSource(f.DataSource, NewSourceMetrics(registry), "contact")
+
+	f.DataSource.EXPECT().Get(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+	f.DataSource.EXPECT().Create(f.Ctx, []byte(f.Key)).Return(f.Response, f.Error).Times(1)
+	f.DataSource.EXPECT().Update(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+	f.DataSource.EXPECT().Patch(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+	f.DataSource.EXPECT().Delete(f.Ctx, []byte(f.Key)).Return(f.Response, nil).Times(1)
+
+	r, err := s.Get(f.Ctx, []byte(f.Key))
+	mocks.CmpError(t, err, nil)
+	if r != f.Response {
+		t.Errorf("Expected correct response.")
+	}
+	_, err = s.Create(f.Ctx, []byte(f.Key))
+	mocks.CmpError(t, err, f.Error)
+	_, _ = s.Update(f.Ctx, []byte(f.Key))
+	_, _ = s.Patch(f.Ctx, []byte(f.Key))
+	_, _ = s.Delete(f.Ctx, []byte(f.Key))
+
+	expectMetrics(t, registry,
+		`data_source_operation_duration_seconds_count{resource="contact",operation="get",result="ok"} 1`,
+		`data_source_operation_duration_seconds_count{resource="contact",operation="create",result="error"} 1`,
+		`data_source_operation_duration_seconds_count{resource="contact",operation="update",result="ok"} 1`,
+		`data_source_operation_duration_seconds_count{resource="contact",operation="patch",result="ok"} 1`,
+		`data_source_operation_duration_seconds_count{resource="contact",operation="delete",result="ok"} 1`,
+	)
+}
+
+func TestInstrumentedCacheSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newCacheSourceFixture(ctrl)
+	registry := metrics.NewRegistry()
+	c := NewInstrumentedCacheSource(f.Cache, NewSourceMetrics(registry), "contact")
+
+	gomock.InOrder(
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(f.Response, f.Policy.Ttl, nil).Times(2),
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), nil).Times(1),
+		f.Cache.EXPECT().Get(f.Partition, f.Key).Return(nil, time.Duration(0), f.Error).Times(1),
+	)
+	f.Cache.EXPECT().Insert(f.Partition, f.Key, f.Response).Return(f.Error).Times(1)
+	f.Cache.EXPECT().Remove(f.Partition, f.Response).Return(nil).Times(1)
+	f.Cache.EXPECT().RemoveKey(f.Partition, f.Key).Return(nil).Times(1)
+
+	r, age, err := c.Get(f.Partition, f.Key)
+	mocks.CmpError(t, err, nil)
+	if r != f.Response || age != f.Policy.Ttl {
+		t.Errorf("Expected correct response.")
+	}
+	_, _, _ = c.Get(f.Partition, f.Key)
+	_, _, _ = c.Get(f.Partition, f.Key)
+	_, _, err = c.Get(f.Partition, f.Key)
+	mocks.CmpError(t, err, f.Error)
+	mocks.CmpError(t, c.Insert(f.Partition, f.Key, f.Response), f.Error)
+	mocks.CmpError(t, c.Remove(f.Partition, f.Response), nil)
+	mocks.CmpError(t, c.RemoveKey(f.Partition, f.Key), nil)
+
+	expectMetrics(t, registry,
+		`cache_operations_total{resource="contact",operation="get",result="hit"} 2`,
+		`cache_operations_total{resource="contact",operation="get",result="miss"} 1`,
+		`cache_operations_total{resource="contact",operation="get",result="error"} 1`,
+		`cache_operations_total{resource="contact",operation="insert",result="error"} 1`,
+		`cache_operations_total{resource="contact",operation="remove",result="ok"} 2`,
+	)
+}
+
+func TestNewInstrumentedHttpDo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newRetryFixture(ctrl)
+	registry := metrics.NewRegistry()
+	do := NewInstrumentedHttpDo(f.Do.Do, NewSourceMetrics(registry), "contact")
+	req := f.request(http.MethodGet, nil)
+	resp := newStatusResponse(http.StatusNotFound, nil)
+
+	gomock.InOrder(
+		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1),
+		f.Do.EXPECT().Do(req).Return(nil, f.Error).Times(1),
+		f.Do.EXPECT().Do(req).Return(nil, ErrCircuitOpen).Times(1),
+	)
+	res, err := do(req)
+	mocks.CmpError(t, err, nil)
+	if res != resp {
+		t.Errorf("Expected correct response.")
+	}
+	_, err = do(req)
+	mocks.CmpError(t, err, f.Error)
+	_, _ = do(req)
+
+	expectMetrics(t, registry,
+		`upstream_request_duration_seconds_count{resource="contact",method="GET",status="404"} 1`,
+		`upstream_request_duration_seconds_count{resource="contact",method="GET",status="error"} 1`,
+		`upstream_request_duration_seconds_count{resource="contact",method="GET",status="circuit_open"} 1`,
+	)
+}
@@ -0,0 +1,89 @@
+package sources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/coldze/test/utils"
+)
+
+//INDEX_NAMESPACE is a first part of keys of indexes, prefixes of resources must differ from it
+const (
+	INDEX_NAMESPACE = "idx"
+)
+
+//ErrIndexNamespace is returned for value keys, that would get into namespace of indexes (only not prefixed and not
+//partitioned keys can)
+var ErrIndexNamespace = errors.New("key is reserved for indexes")
+
+//Partitioner returns identity of a caller (tenant), cached values are not shared between different tenants.
+//Empty partition means that cache is shared.
+type Partitioner func(ctx context.Context) string
+
+func NoPartitioner(ctx context.Context) string {
+	return ""
+}
+
+//NewHeaderPartitioner derives tenant from values of provided request headers (for example, API key).
+//Values are hashed, so credentials are never stored in cache keys in clear.
+func NewHeaderPartitioner(names []string) Partitioner {
+	if len(names) == 0 {
+		return NoPartitioner
+	}
+	return func(ctx context.Context) string {
+		headers := utils.GetHeaders(ctx)
+		if headers == nil {
+			headers = http.Header{}
+		}
+		h := sha256.New()
+		for _, name := range names {
+			_, _ = h.Write([]byte(http.CanonicalHeaderKey(name)))
+			_, _ = h.Write([]byte{0})
+			for _, v := range headers[http.CanonicalHeaderKey(name)] {
+				_, _ = h.Write([]byte(v))
+				_, _ = h.Write([]byte{0})
+			}
+			_, _ = h.Write([]byte{'\n'})
+		}
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+//NewPrefixedPartitioner keeps values of different resources apart, even if they have the same keys
+func NewPrefixedPartitioner(prefix string, partition Partitioner) Partitioner {
+	return func(ctx context.Context) string {
+		tenant := partition(ctx)
+		if len(tenant) == 0 {
+			return prefix
+		}
+		return partitionedKey(prefix, tenant)
+	}
+}
+
+//IndexKey is a set of cache keys of value in all partitions of resource, so value can be removed for all tenants
+//without scan of keyspace. Prefix is a part of partition, that is common for all tenants (name of resource).
+//Indexes have their own namespace, so keys of values never collide with them (see valueKey).
+func IndexKey(prefix string, key string) string {
+	return INDEX_NAMESPACE + ":" + partitionedKey(prefix, key)
+}
+
+//valueKey is a key of cached value. Prefixes of resources and partitions of tenants (hashes) never match namespace of
+//indexes, so only not prefixed and not partitioned key can get into it - it's rejected.
+func valueKey(partition string, key string) (string, error) {
+	res := partitionedKey(partition, key)
+	if strings.HasPrefix(res, INDEX_NAMESPACE+":") {
+		return "", ErrIndexNamespace
+	}
+	return res, nil
+}
+
+func partitionedKey(partition string, key string) string {
+	if len(partition) == 0 {
+		return key
+	}
+	return partition + ":" + key
+}
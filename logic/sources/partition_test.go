@@ -0,0 +1,110 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/utils"
+)
+
+func newHeadersContext(apiKey string) context.Context {
+	headers := http.Header{}
+	headers.Set("Autopilotapikey", apiKey)
+	return utils.SetHeaders(context.Background(), headers)
+}
+
+func TestNewHeaderPartitioner(t *testing.T) {
+
+	t.Run("no headers - no partitioning", func(t *testing.T) {
+		partition := NewHeaderPartitioner(nil)
+		if partition(newHeadersContext("key-a")) != "" {
+			t.Errorf("Expected empty partition")
+		}
+	})
+
+	t.Run("different credentials - different partitions", func(t *testing.T) {
+		partition := NewHeaderPartitioner([]string{"autopilotapikey"})
+		a := partition(newHeadersContext("key-a"))
+		b := partition(newHeadersContext("key-b"))
+		if a == b {
+			t.Errorf("Partitions are equal: %v", a)
+		}
+		if a != partition(newHeadersContext("key-a")) {
+			t.Errorf("Partition is not stable")
+		}
+	})
+
+	t.Run("credentials are not stored in clear", func(t *testing.T) {
+		partition := NewHeaderPartitioner([]string{"autopilotapikey"})
+		res := partition(newHeadersContext("secret-key"))
+		if len(res) == 0 || strings.Contains(res, "secret-key") {
+			t.Errorf("Unexpected partition: %v", res)
+		}
+	})
+
+	t.Run("missing headers are a separate partition", func(t *testing.T) {
+		partition := NewHeaderPartitioner([]string{"autopilotapikey"})
+		empty := partition(context.Background())
+		if len(empty) == 0 || empty == partition(newHeadersContext("key-a")) {
+			t.Errorf("Unexpected partition: %v", empty)
+		}
+	})
+}
+
+func TestPartitionedKey(t *testing.T) {
+	if partitionedKey("", "id") != "id" {
+		t.Errorf("Key without partition is modified")
+	}
+	if partitionedKey("tenant", "id") != "tenant:id" {
+		t.Errorf("Unexpected key: %v", partitionedKey("tenant", "id"))
+	}
+}
+
+func TestNewPrefixedPartitioner(t *testing.T) {
+	ctx := newHeadersContext("key-a")
+	tenant := NewHeaderPartitioner([]string{"autopilotapikey"})(ctx)
+	res := NewPrefixedPartitioner("lists", NewHeaderPartitioner([]string{"autopilotapikey"}))(ctx)
+	if res != "lists:"+tenant {
+		t.Errorf("Unexpected partition: %v", res)
+	}
+	res = NewPrefixedPartitioner("lists", NoPartitioner)(ctx)
+	if res != "lists" {
+		t.Errorf("Unexpected partition without tenant: %v", res)
+	}
+}
+
+func TestIndexKey(t *testing.T) {
+	if IndexKey("lists", "id") != "idx:lists:id" {
+		t.Errorf("Unexpected key: %v", IndexKey("lists", "id"))
+	}
+	if IndexKey("", "id") != "idx:id" {
+		t.Errorf("Unexpected key without prefix: %v", IndexKey("", "id"))
+	}
+}
+
+func TestValueKey(t *testing.T) {
+	cases := []struct {
+		partition string
+		key       string
+		expected  string
+		err       error
+	}{
+		{"", "42", "42", nil},
+		{"contact", "idx:42", "contact:idx:42", nil},
+		{"", "idx", "idx", nil},
+		{"", "idx:42", "", ErrIndexNamespace},
+	}
+	for _, c := range cases {
+		res, err := valueKey(c.partition, c.key)
+		mocks.CmpError(t, err, c.err)
+		if res != c.expected {
+			t.Errorf("Partition '%v', key '%v'. Expected: %v. Got: %v", c.partition, c.key, c.expected, res)
+		}
+		if c.err == nil && res == IndexKey(c.partition, c.key) {
+			t.Errorf("Partition '%v', key '%v'. Value key collides with index.", c.partition, c.key)
+		}
+	}
+}
@@ -20,24 +20,57 @@ type redisCacheSource struct {
 	parse          DataParser
 	cache          RedisWrap
 	ttl            time.Duration
+	headers        []string
+	prefix         string
+	now            func() time.Time
 }
 
-func (r *redisCacheSource) Get(key string) (logic.Response, error) {
-	rawData, err := r.cache.Get(key)
+//age of plain-body entries is calculated from remaining ttl, as every value is stored with the same ttl
+func (r *redisCacheSource) age(remaining time.Duration) time.Duration {
+	if remaining < 0 || remaining > r.ttl {
+		return 0
+	}
+	return r.ttl - remaining
+}
+
+func (r *redisCacheSource) entryAge(entry *cacheEntry) time.Duration {
+	age := r.now().Sub(entry.FetchedAt)
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+func (r *redisCacheSource) Get(partition string, key string) (logic.Response, time.Duration, error) {
+	cacheKey, err := valueKey(partition, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	rawData, remaining, err := r.cache.GetWithTtl(cacheKey)
 	if err == redis.Nil {
-		return nil, nil
+		return nil, 0, nil
 	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	data, ok := rawData.(string)
 	if !ok {
-		return nil, fmt.Errorf("cached data is not of type string, it's type is: %T", rawData)
+		return nil, 0, fmt.Errorf("cached data is not of type string, it's type is: %T", rawData)
+	}
+	if !isCacheEntry(data) {
+		//plain-body entry, stored by previous version of the service
+		res, err := r.createResponse([]byte(data))
+		return res, r.age(remaining), err
+	}
+	entry, err := decodeCacheEntry(data)
+	if err != nil {
+		return nil, 0, err
 	}
-	return r.createResponse([]byte(data))
+	res, err := logic.NewHttpResponse(entry.Body, entry.Headers, entry.Status)
+	return res, r.entryAge(entry), err
 }
 
-func (r *redisCacheSource) decode(response logic.Response) ([]byte, *logic.Contact, error) {
+func (r *redisCacheSource) build(response logic.Response) (logic.DataBuilder, []byte, error) {
 	b := r.createBuilder()
 	if b == nil {
 		return nil, nil, errors.New("internal error - builder is nil")
@@ -50,35 +83,71 @@ func (r *redisCacheSource) decode(response logic.Response) ([]byte, *logic.Conta
 	if err != nil {
 		return nil, nil, err
 	}
+	return b, data, nil
+}
+
+func (r *redisCacheSource) decode(response logic.Response) (logic.DataBuilder, []byte, *logic.Contact, error) {
+	b, data, err := r.build(response)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	contact, err := r.parse(data)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, fmt.Errorf("failed to get key of value: %w", err)
+	}
+	return b, data, &contact, nil
+}
+
+func (r *redisCacheSource) Remove(partition string, response logic.Response) error {
+	_, _, contact, err := r.decode(response)
+	if err != nil {
+		return err
 	}
-	return data, &contact, nil
+	return r.RemoveKey(partition, contact.ID)
 }
 
-func (r *redisCacheSource) Remove(response logic.Response) error {
-	_, contact, err := r.decode(response)
+func (r *redisCacheSource) RemoveKey(partition string, key string) error {
+	cacheKey, err := valueKey(partition, key)
 	if err != nil {
 		return err
 	}
-	return r.cache.Del(contact.ID)
+	return r.cache.Del(cacheKey)
 }
 
-func (r *redisCacheSource) Insert(response logic.Response) error {
-	data, contact, err := r.decode(response)
+func (r *redisCacheSource) Insert(partition string, key string, response logic.Response) error {
+	cacheKey, err := valueKey(partition, key)
+	if err != nil {
+		return err
+	}
+	b, data, err := r.build(response)
+	if err != nil {
+		return err
+	}
+	entry, err := encodeCacheEntry(&cacheEntry{
+		Version:   cache_entry_version,
+		Status:    b.StatusCode(),
+		Headers:   filterHeaders(b.Header(), r.headers),
+		Body:      data,
+		FetchedAt: r.now().UTC(),
+	})
 	if err != nil {
 		return err
 	}
-	return r.cache.Set(contact.ID, data, r.ttl)
+	return r.cache.SetIndexed(cacheKey, entry, r.ttl, IndexKey(r.prefix, key))
 }
 
-func NewRedisCacheSource(cache RedisWrap, ttl time.Duration) CacheSource {
+//headers - list of response headers, that are cached together with body and status code,
+//parse - gets key of cached value from response body, when it's removed by response of update,
+//prefix - part of partition, that is common for all tenants, values of all tenants are indexed by it (see IndexKey)
+func NewRedisCacheSource(cache RedisWrap, ttl time.Duration, headers []string, parse DataParser, prefix string) CacheSource {
 	return &redisCacheSource{
 		cache:          cache,
 		createResponse: logic.NewJsonOkResponse,
 		createBuilder:  NewHttpDataBuilder,
-		parse:          logic.ParseContact,
+		parse:          parse,
 		ttl:            ttl,
+		headers:        headers,
+		prefix:         prefix,
+		now:            time.Now,
 	}
 }
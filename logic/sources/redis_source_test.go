@@ -2,11 +2,14 @@ package sources
 
 import (
 	"errors"
+	"github.com/coldze/test/consts"
 	"github.com/coldze/test/logic"
 	"github.com/coldze/test/mocks"
 	"github.com/coldze/test/mocks/mock_sources"
 	"github.com/go-redis/redis"
 	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -15,8 +18,11 @@ type redisCacheFixture struct {
 	Contact            logic.Contact
 	Data               string
 	Key                string
+	Partition          string
 	Error              error
 	Ttl                time.Duration
+	Now                time.Time
+	Headers            []string
 	Response           *mocks.MockResponse
 	DataBuilder        *mock_sources.MockDataBuilder
 	RedisWrap          *mock_sources.MockRedisWrap
@@ -30,8 +36,11 @@ func newRedisCacheFixture(ctrl *gomock.Controller) *redisCacheFixture {
 		Contact:            logic.Contact{ID: "some random key"},
 		Data:               "some random data",
 		Key:                "some test key",
+		Partition:          "some test partition",
 		Error:              errors.New("some test error"),
 		Ttl:                1 * time.Second,
+		Now:                time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC),
+		Headers:            []string{consts.HEADER_CONTENT_TYPE},
 		Response:           mocks.NewMockResponse(ctrl),
 		DataBuilder:        mock_sources.NewMockDataBuilder(ctrl),
 		RedisWrap:          mock_sources.NewMockRedisWrap(ctrl),
@@ -48,9 +57,37 @@ func newRedisCacheSource(f *redisCacheFixture) *redisCacheSource {
 		parse:          f.DataParser.Parse,
 		createBuilder:  f.DataBuilderFactory.Create,
 		createResponse: f.CreateResponse.Create,
+		headers:        f.Headers,
+		prefix:         "contact",
+		now: func() time.Time {
+			return f.Now
+		},
 	}
 }
 
+func (f *redisCacheFixture) entry(t *testing.T) []byte {
+	headers := http.Header{}
+	headers.Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+	data, err := encodeCacheEntry(&cacheEntry{
+		Version:   cache_entry_version,
+		Status:    http.StatusCreated,
+		Headers:   headers,
+		Body:      []byte(f.Data),
+		FetchedAt: f.Now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode entry: %v", err)
+	}
+	return data
+}
+
+func (f *redisCacheFixture) builderHeaders() http.Header {
+	headers := http.Header{}
+	headers.Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+	headers.Set("Set-Cookie", "not cached")
+	return headers
+}
+
 func TestRedisCacheSource_Get(t *testing.T) {
 
 	t.Run("get error is a failure", func(t *testing.T) {
@@ -60,8 +97,8 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return(nil, f.Error).Times(1)
-		r, err := c.Get(f.Key)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(nil, time.Duration(0), f.Error).Times(1)
+		r, _, err := c.Get(f.Partition, f.Key)
 		mocks.CmpError(t, err, f.Error)
 		if r != nil {
 			t.Errorf("Response should be nil.")
@@ -75,8 +112,8 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return(nil, redis.Nil).Times(1)
-		r, err := c.Get(f.Key)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(nil, time.Duration(0), redis.Nil).Times(1)
+		r, _, err := c.Get(f.Partition, f.Key)
 		mocks.CmpError(t, err, nil)
 		if r != nil {
 			t.Errorf("Response should be nil.")
@@ -90,8 +127,8 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return([]byte(f.Data), nil).Times(1)
-		r, err := c.Get(f.Key)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return([]byte(f.Data), f.Ttl, nil).Times(1)
+		r, _, err := c.Get(f.Partition, f.Key)
 		if err == nil {
 			t.Errorf("Error is nil.")
 		}
@@ -107,9 +144,9 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return(f.Data, nil).Times(1)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(f.Data, f.Ttl, nil).Times(1)
 		f.CreateResponse.EXPECT().Create([]byte(f.Data)).Return(f.Response, nil).Times(1)
-		r, err := c.Get(f.Key)
+		r, _, err := c.Get(f.Partition, f.Key)
 		mocks.CmpError(t, err, nil)
 		if r != f.Response {
 			t.Errorf("Expected correct response.")
@@ -123,9 +160,9 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return(f.Data, nil).Times(1)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(f.Data, f.Ttl, nil).Times(1)
 		f.CreateResponse.EXPECT().Create([]byte(f.Data)).Return(nil, nil).Times(1)
-		r, err := c.Get(f.Key)
+		r, _, err := c.Get(f.Partition, f.Key)
 		mocks.CmpError(t, err, nil)
 		if r != nil {
 			t.Errorf("Response should be nil.")
@@ -139,9 +176,9 @@ func TestRedisCacheSource_Get(t *testing.T) {
 		f := newRedisCacheFixture(ctrl)
 		c := newRedisCacheSource(f)
 
-		f.RedisWrap.EXPECT().Get(f.Key).Return(f.Data, nil).Times(1)
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(f.Data, f.Ttl, nil).Times(1)
 		f.CreateResponse.EXPECT().Create([]byte(f.Data)).Return(nil, f.Error).Times(1)
-		r, err := c.Get(f.Key)
+		r, _, err := c.Get(f.Partition, f.Key)
 		mocks.CmpError(t, err, f.Error)
 		if r != nil {
 			t.Errorf("Response should be nil.")
@@ -149,6 +186,76 @@ func TestRedisCacheSource_Get(t *testing.T) {
 	})
 }
 
+func TestRedisCacheSource_GetEntry(t *testing.T) {
+
+	t.Run("cache entry is restored with status and headers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRedisCacheFixture(ctrl)
+		c := newRedisCacheSource(f)
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(string(f.entry(t)), f.Ttl, nil).Times(1)
+		f.Now = f.Now.Add(time.Minute)
+		r, age, err := c.Get(f.Partition, f.Key)
+		mocks.CmpError(t, err, nil)
+		if age != time.Minute {
+			t.Errorf("Unexpected age: %v", age)
+		}
+		rec := httptest.NewRecorder()
+		err = r.Write(rec)
+		mocks.CmpError(t, err, nil)
+		if rec.Code != http.StatusCreated {
+			t.Errorf("Unexpected status: %v", rec.Code)
+		}
+		if rec.Header().Get(consts.HEADER_CONTENT_TYPE) != consts.MIME_APPLICATION_JSON {
+			t.Errorf("Unexpected headers: %+v", rec.Header())
+		}
+		if rec.Body.String() != f.Data {
+			t.Errorf("Unexpected body: %v", rec.Body.String())
+		}
+	})
+
+	t.Run("broken cache entry is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRedisCacheFixture(ctrl)
+		c := newRedisCacheSource(f)
+
+		f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(cache_entry_prefix+f.Data, f.Ttl, nil).Times(1)
+		r, _, err := c.Get(f.Partition, f.Key)
+		if err == nil {
+			t.Errorf("Error is nil.")
+		}
+		if r != nil {
+			t.Errorf("Response should be nil.")
+		}
+	})
+}
+
+func TestRedisCacheSource_Age(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newRedisCacheFixture(ctrl)
+	c := newRedisCacheSource(f)
+
+	f.RedisWrap.EXPECT().GetWithTtl(f.Partition+":"+f.Key).Return(f.Data, f.Ttl/4, nil).Times(1)
+	f.CreateResponse.EXPECT().Create([]byte(f.Data)).Return(f.Response, nil).Times(1)
+	_, age, err := c.Get(f.Partition, f.Key)
+	mocks.CmpError(t, err, nil)
+	if age != f.Ttl*3/4 {
+		t.Errorf("Unexpected age: %v", age)
+	}
+
+	for _, remaining := range []time.Duration{-time.Millisecond, f.Ttl * 2} {
+		if c.age(remaining) != 0 {
+			t.Errorf("Expected zero age for remaining ttl: %v", remaining)
+		}
+	}
+}
+
 func TestRedisCacheSource_Remove(t *testing.T) {
 
 	t.Run("nil builder is a failure", func(t *testing.T) {
@@ -160,7 +267,7 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 
 		f.DataBuilderFactory.EXPECT().Create().Return(nil).Times(1)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		if err == nil {
 			t.Errorf("Error is nil")
 		}
@@ -176,7 +283,7 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 		f.DataBuilderFactory.EXPECT().Create().Return(f.DataBuilder).Times(1)
 		f.Response.EXPECT().Write(f.DataBuilder).Return(f.Error).Times(1)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -191,7 +298,7 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), f.Error).Times(1)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -207,7 +314,7 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
 		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, f.Error).Times(1)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -222,9 +329,9 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
 		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, nil).Times(1)
-		f.RedisWrap.EXPECT().Del(f.Contact.ID).Return(f.Error)
+		f.RedisWrap.EXPECT().Del(f.Partition + ":" + f.Contact.ID).Return(f.Error)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -239,13 +346,46 @@ func TestRedisCacheSource_Remove(t *testing.T) {
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
 		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, nil).Times(1)
-		f.RedisWrap.EXPECT().Del(f.Contact.ID).Return(nil)
+		f.RedisWrap.EXPECT().Del(f.Partition + ":" + f.Contact.ID).Return(nil)
 
-		err := c.Remove(f.Response)
+		err := c.Remove(f.Partition, f.Response)
 		mocks.CmpError(t, err, nil)
 	})
 }
 
+func TestRedisCacheSource_RemoveKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newRedisCacheFixture(ctrl)
+	c := newRedisCacheSource(f)
+
+	f.RedisWrap.EXPECT().Del(f.Partition + ":" + f.Key).Return(f.Error).Times(1)
+	err := c.RemoveKey(f.Partition, f.Key)
+	mocks.CmpError(t, err, f.Error)
+}
+
+func TestRedisCacheSource_IndexNamespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newRedisCacheFixture(ctrl)
+	c := newRedisCacheSource(f)
+	key := INDEX_NAMESPACE + ":42"
+
+	_, _, err := c.Get("", key)
+	mocks.CmpError(t, err, ErrIndexNamespace)
+	err = c.RemoveKey("", key)
+	mocks.CmpError(t, err, ErrIndexNamespace)
+	err = c.Insert("", key, f.Response)
+	mocks.CmpError(t, err, ErrIndexNamespace)
+
+	//partitioned key can't get into namespace of indexes
+	f.RedisWrap.EXPECT().Del(f.Partition + ":" + key).Return(nil).Times(1)
+	err = c.RemoveKey(f.Partition, key)
+	mocks.CmpError(t, err, nil)
+}
+
 func TestRedisCacheSource_Insert(t *testing.T) {
 	t.Run("nil builder is a failure", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -256,7 +396,7 @@ func TestRedisCacheSource_Insert(t *testing.T) {
 
 		f.DataBuilderFactory.EXPECT().Create().Return(nil).Times(1)
 
-		err := c.Insert(f.Response)
+		err := c.Insert(f.Partition, f.Key, f.Response)
 		if err == nil {
 			t.Errorf("Error is nil")
 		}
@@ -272,7 +412,7 @@ func TestRedisCacheSource_Insert(t *testing.T) {
 		f.DataBuilderFactory.EXPECT().Create().Return(f.DataBuilder).Times(1)
 		f.Response.EXPECT().Write(f.DataBuilder).Return(f.Error).Times(1)
 
-		err := c.Insert(f.Response)
+		err := c.Insert(f.Partition, f.Key, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -287,23 +427,7 @@ func TestRedisCacheSource_Insert(t *testing.T) {
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), f.Error).Times(1)
 
-		err := c.Insert(f.Response)
-		mocks.CmpError(t, err, f.Error)
-	})
-
-	t.Run("parser error is a failure", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
-
-		f := newRedisCacheFixture(ctrl)
-		c := newRedisCacheSource(f)
-
-		f.DataBuilderFactory.EXPECT().Create().Return(f.DataBuilder).Times(1)
-		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
-		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
-		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, f.Error).Times(1)
-
-		err := c.Insert(f.Response)
+		err := c.Insert(f.Partition, f.Key, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -317,10 +441,11 @@ func TestRedisCacheSource_Insert(t *testing.T) {
 		f.DataBuilderFactory.EXPECT().Create().Return(f.DataBuilder).Times(1)
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
-		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, nil).Times(1)
-		f.RedisWrap.EXPECT().Set(f.Contact.ID, []byte(f.Data), f.Ttl).Return(f.Error)
+		f.DataBuilder.EXPECT().StatusCode().Return(http.StatusCreated).Times(1)
+		f.DataBuilder.EXPECT().Header().Return(f.builderHeaders()).Times(1)
+		f.RedisWrap.EXPECT().SetIndexed(f.Partition+":"+f.Key, f.entry(t), f.Ttl, "idx:contact:"+f.Key).Return(f.Error)
 
-		err := c.Insert(f.Response)
+		err := c.Insert(f.Partition, f.Key, f.Response)
 		mocks.CmpError(t, err, f.Error)
 	})
 
@@ -334,10 +459,11 @@ func TestRedisCacheSource_Insert(t *testing.T) {
 		f.DataBuilderFactory.EXPECT().Create().Return(f.DataBuilder).Times(1)
 		f.Response.EXPECT().Write(f.DataBuilder).Return(nil).Times(1)
 		f.DataBuilder.EXPECT().Build().Return([]byte(f.Data), nil).Times(1)
-		f.DataParser.EXPECT().Create([]byte(f.Data)).Return(f.Contact, nil).Times(1)
-		f.RedisWrap.EXPECT().Set(f.Contact.ID, []byte(f.Data), f.Ttl).Return(nil)
+		f.DataBuilder.EXPECT().StatusCode().Return(http.StatusCreated).Times(1)
+		f.DataBuilder.EXPECT().Header().Return(f.builderHeaders()).Times(1)
+		f.RedisWrap.EXPECT().SetIndexed(f.Partition+":"+f.Key, f.entry(t), f.Ttl, "idx:contact:"+f.Key).Return(nil)
 
-		err := c.Insert(f.Response)
+		err := c.Insert(f.Partition, f.Key, f.Response)
 		mocks.CmpError(t, err, nil)
 	})
 }
@@ -348,7 +474,7 @@ func TestNewRedisCacheSource(t *testing.T) {
 
 	redisWrap := mock_sources.NewMockRedisWrap(ctrl)
 
-	res := NewRedisCacheSource(redisWrap, 1*time.Second)
+	res := NewRedisCacheSource(redisWrap, 1*time.Second, nil, logic.ParseContact, "contact")
 	if res == nil {
 		t.Errorf("Factory returns nil")
 	}
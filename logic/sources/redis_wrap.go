@@ -11,6 +11,16 @@ type RedisWrap interface {
 	Set(key string, data interface{}, ttl time.Duration) error
 	Del(key string) error
 	Get(key string) (interface{}, error)
+	GetWithTtl(key string) (interface{}, time.Duration, error)
+	//Scan returns next batch of keys, matching pattern, and cursor of the next call. Zero cursor means scan is complete.
+	Scan(cursor uint64, pattern string, count int64) ([]string, uint64, error)
+	//DelKeys returns number of removed keys
+	DelKeys(keys ...string) (int64, error)
+	//SetIndexed stores value and adds its key to index (set of keys), index lives as long as its latest value
+	SetIndexed(key string, data interface{}, ttl time.Duration, index string) error
+	//TakeIndex returns keys of index and removes index in one transaction
+	TakeIndex(index string) ([]string, error)
+	Ping() (string, error)
 	Close() error
 }
 
@@ -30,6 +40,55 @@ func (r *redisWrapImpl) Get(key string) (interface{}, error) {
 	return r.client.Get(key).Result()
 }
 
+//GetWithTtl returns value and remaining time to live in one round-trip. Negative ttl means that key has no expiration.
+func (r *redisWrapImpl) GetWithTtl(key string) (interface{}, time.Duration, error) {
+	pipe := r.client.Pipeline()
+	get := pipe.Get(key)
+	ttl := pipe.PTTL(key)
+	_, _ = pipe.Exec()
+	data, err := get.Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	remaining, err := ttl.Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, remaining, nil
+}
+
+//SCAN doesn't block redis, unlike KEYS, but the same key might be returned more than once
+func (r *redisWrapImpl) Scan(cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	return r.client.Scan(cursor, pattern, count).Result()
+}
+
+func (r *redisWrapImpl) DelKeys(keys ...string) (int64, error) {
+	return r.client.Del(keys...).Result()
+}
+
+func (r *redisWrapImpl) SetIndexed(key string, data interface{}, ttl time.Duration, index string) error {
+	_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(key, data, ttl)
+		pipe.SAdd(index, key)
+		pipe.Expire(index, ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *redisWrapImpl) TakeIndex(index string) ([]string, error) {
+	var members *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		members = pipe.SMembers(index)
+		pipe.Del(index)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members.Result()
+}
+
 func (r *redisWrapImpl) Close() error {
 	return r.client.Close()
 }
@@ -13,14 +13,19 @@ type RequestFactory func(ctx context.Context, data []byte, url string, method st
 
 func DefaultRequestFactory(ctx context.Context, data []byte, url string, method string) (*http.Request, error) {
 	r := bytes.NewReader(data)
-	req, err := http.NewRequest(method, url, r)
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
 	if err != nil {
 		return nil, err
 	}
 	headers := utils.GetHeaders(ctx)
 	if headers != nil {
-		req.Header = headers
+		//headers of incoming request are not modified
+		req.Header = headers.Clone()
 	}
 	req.Header.Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+	requestId := utils.GetRequestId(ctx)
+	if len(requestId) > 0 {
+		req.Header.Set(consts.HEADER_REQUEST_ID, requestId)
+	}
 	return req, nil
 }
@@ -0,0 +1,187 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coldze/test/utils"
+)
+
+const (
+	header_retry_after = "Retry-After"
+)
+
+//RetryPolicy describes how failed calls to external API are repeated.
+//Only requests with methods from Methods are repeated, non-idempotent methods should be added explicitly.
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+	Jitter            float64
+	RetryableStatuses []int
+	Methods           []string
+}
+
+func (p *RetryPolicy) isRetryableMethod(method string) bool {
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) isRetryableStatus(code int) bool {
+	for _, c := range p.RetryableStatuses {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+//backoff is exponential (BaseBackoff * 2^attempt, limited with MaxBackoff), Jitter part of it is random.
+func (p *RetryPolicy) backoff(attempt int, random float64) time.Duration {
+	delay := p.BaseBackoff
+	for i := 0; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	jitter := time.Duration(float64(delay) * p.Jitter * random)
+	return delay - time.Duration(float64(delay)*p.Jitter) + jitter
+}
+
+//parseRetryAfter supports both formats of Retry-After: delay in seconds and HTTP-date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+type sleepFunc func(ctx context.Context, d time.Duration) error
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type retryingHttpDo struct {
+	do     HttpDo
+	policy RetryPolicy
+	random func() float64
+	sleep  sleepFunc
+	now    func() time.Time
+}
+
+func discardBody(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+//delay returns how long to wait before next attempt and whether the call should be repeated at all
+func (r *retryingHttpDo) delay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt+1 >= r.policy.MaxAttempts {
+		return 0, false
+	}
+	if err != nil {
+		return r.policy.backoff(attempt, r.random()), true
+	}
+	if !r.policy.isRetryableStatus(resp.StatusCode) {
+		return 0, false
+	}
+	retryAfter, ok := parseRetryAfter(resp.Header.Get(header_retry_after), r.now())
+	if !ok {
+		return r.policy.backoff(attempt, r.random()), true
+	}
+	//there is no sense to wait longer, than we're allowed to, response is returned as is
+	if retryAfter > r.policy.MaxBackoff {
+		return 0, false
+	}
+	return retryAfter, true
+}
+
+func (r *retryingHttpDo) Do(req *http.Request) (*http.Response, error) {
+	if !r.policy.isRetryableMethod(req.Method) {
+		return r.do(req)
+	}
+	//body has to be replayed between attempts, if it can't be - request is sent only once
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return r.do(req)
+	}
+	ctx := req.Context()
+	logger := utils.GetLogger(ctx)
+	attempt := 0
+	for {
+		resp, err := r.do(req)
+		if err != nil && (ctx.Err() != nil || errors.Is(err, ErrCircuitOpen)) {
+			return resp, err
+		}
+		delay, retry := r.delay(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if err != nil {
+			logger.Warningf("Attempt %v of %v %v failed, retrying in %v. Error: %v", attempt+1, req.Method, req.URL, delay, err)
+		} else {
+			logger.Warningf("Attempt %v of %v %v failed, retrying in %v. Status: %v", attempt+1, req.Method, req.URL, delay, resp.StatusCode)
+		}
+		discardBody(resp)
+		err = r.sleep(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		attempt++
+	}
+}
+
+func NewRetryingHttpDo(do HttpDo, policy RetryPolicy) HttpDo {
+	r := &retryingHttpDo{
+		do:     do,
+		policy: policy,
+		random: rand.Float64,
+		sleep:  sleepWithContext,
+		now:    time.Now,
+	}
+	return r.Do
+}
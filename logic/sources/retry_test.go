@@ -0,0 +1,324 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+	"github.com/coldze/test/mocks/mock_std"
+	"github.com/coldze/test/utils"
+)
+
+type retryFixture struct {
+	Url    string
+	Data   string
+	Error  error
+	Ctx    context.Context
+	Logger *mock_logs.MockLogger
+	Do     *mock_std.MockHttpWrap
+	Sleeps []time.Duration
+	Policy RetryPolicy
+}
+
+func newRetryFixture(ctrl *gomock.Controller) *retryFixture {
+	logger := mock_logs.NewMockLogger(ctrl)
+	return &retryFixture{
+		Url:    "https://test.url.com/v1/api",
+		Data:   "some random data",
+		Error:  errors.New("some test error"),
+		Ctx:    utils.SetLogger(context.Background(), logger),
+		Logger: logger,
+		Do:     mock_std.NewMockHttpWrap(ctrl),
+		Policy: RetryPolicy{
+			MaxAttempts:       3,
+			BaseBackoff:       100 * time.Millisecond,
+			MaxBackoff:        time.Second,
+			RetryableStatuses: []int{http.StatusServiceUnavailable},
+			Methods:           []string{http.MethodGet},
+		},
+	}
+}
+
+func newTestableRetryingHttpDo(f *retryFixture) *retryingHttpDo {
+	return &retryingHttpDo{
+		do:     f.Do.Do,
+		policy: f.Policy,
+		random: func() float64 {
+			return 0.5
+		},
+		sleep: func(ctx context.Context, d time.Duration) error {
+			f.Sleeps = append(f.Sleeps, d)
+			return ctx.Err()
+		},
+		now: func() time.Time {
+			return time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+func newStatusResponse(code int, headers http.Header) *http.Response {
+	rec := httptest.NewRecorder()
+	for k, v := range headers {
+		rec.Header()[k] = v
+	}
+	rec.WriteHeader(code)
+	return rec.Result()
+}
+
+func (f *retryFixture) request(method string, body []byte) *http.Request {
+	req, err := http.NewRequestWithContext(f.Ctx, method, f.Url, bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Jitter:      0.5,
+	}
+	cases := []struct {
+		attempt  int
+		random   float64
+		expected time.Duration
+	}{
+		{0, 1, 100 * time.Millisecond},
+		{0, 0, 50 * time.Millisecond},
+		{2, 1, 400 * time.Millisecond},
+		{10, 1, time.Second},
+		{10, 0.5, 750 * time.Millisecond},
+	}
+	for _, c := range cases {
+		res := p.backoff(c.attempt, c.random)
+		if res != c.expected {
+			t.Errorf("Attempt %v, random %v. Expected: %v. Got: %v", c.attempt, c.random, c.expected, res)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-3", 0, false},
+		{"soon", 0, false},
+		{now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second, true},
+		{now.Add(-5 * time.Second).Format(http.TimeFormat), 0, true},
+	}
+	for _, c := range cases {
+		res, ok := parseRetryAfter(c.value, now)
+		if res != c.expected || ok != c.ok {
+			t.Errorf("Value '%v'. Expected: %v, %v. Got: %v, %v", c.value, c.expected, c.ok, res, ok)
+		}
+	}
+}
+
+func TestRetryingHttpDo_Do(t *testing.T) {
+
+	t.Run("success is not retried", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+		resp := newStatusResponse(http.StatusOK, nil)
+
+		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
+		res, err := r.Do(req)
+		mocks.CmpError(t, err, nil)
+		if res != resp || len(f.Sleeps) != 0 {
+			t.Errorf("Unexpected result: %v, sleeps: %v", res, f.Sleeps)
+		}
+	})
+
+	t.Run("errors are retried until max attempts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+
+		f.Do.EXPECT().Do(gomock.Any()).Return(nil, f.Error).Times(3)
+		f.Logger.EXPECT().Warningf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), f.Error).Times(2)
+		_, err := r.Do(req)
+		mocks.CmpError(t, err, f.Error)
+		if len(f.Sleeps) != 2 || f.Sleeps[0] != 100*time.Millisecond || f.Sleeps[1] != 200*time.Millisecond {
+			t.Errorf("Unexpected sleeps: %v", f.Sleeps)
+		}
+	})
+
+	t.Run("retryable status is retried, body is replayed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		f.Policy.Methods = append(f.Policy.Methods, http.MethodPost)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodPost, []byte(f.Data))
+		failed := newStatusResponse(http.StatusServiceUnavailable, nil)
+		resp := newStatusResponse(http.StatusOK, nil)
+
+		bodies := []string{}
+		readBody := func(req *http.Request) {
+			data, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Errorf("Failed to read body: %v", err)
+			}
+			bodies = append(bodies, string(data))
+		}
+		gomock.InOrder(
+			f.Do.EXPECT().Do(gomock.Any()).Do(readBody).Return(failed, nil).Times(1),
+			f.Do.EXPECT().Do(gomock.Any()).Do(readBody).Return(resp, nil).Times(1),
+		)
+		f.Logger.EXPECT().Warningf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), http.StatusServiceUnavailable).Times(1)
+		res, err := r.Do(req)
+		mocks.CmpError(t, err, nil)
+		if res != resp {
+			t.Errorf("Expected correct response.")
+		}
+		if len(bodies) != 2 || bodies[0] != f.Data || bodies[1] != f.Data {
+			t.Errorf("Body is not replayed: %v", bodies)
+		}
+	})
+
+	t.Run("not retryable status is returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+		resp := newStatusResponse(http.StatusInternalServerError, nil)
+
+		f.Do.EXPECT().Do(req).Return(resp, nil).Times(1)
+		res, err := r.Do(req)
+		mocks.CmpError(t, err, nil)
+		if res != resp {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("not retryable method is sent once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodPost, []byte(f.Data))
+
+		f.Do.EXPECT().Do(req).Return(nil, f.Error).Times(1)
+		_, err := r.Do(req)
+		mocks.CmpError(t, err, f.Error)
+	})
+
+	t.Run("retry-after is honored", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+		failed := newStatusResponse(http.StatusServiceUnavailable, http.Header{header_retry_after: []string{"1"}})
+		resp := newStatusResponse(http.StatusOK, nil)
+
+		gomock.InOrder(
+			f.Do.EXPECT().Do(gomock.Any()).Return(failed, nil).Times(1),
+			f.Do.EXPECT().Do(gomock.Any()).Return(resp, nil).Times(1),
+		)
+		f.Logger.EXPECT().Warningf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), http.StatusServiceUnavailable).Times(1)
+		_, err := r.Do(req)
+		mocks.CmpError(t, err, nil)
+		if len(f.Sleeps) != 1 || f.Sleeps[0] != time.Second {
+			t.Errorf("Unexpected sleeps: %v", f.Sleeps)
+		}
+	})
+
+	t.Run("retry-after longer than max backoff is not retried", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+		failed := newStatusResponse(http.StatusServiceUnavailable, http.Header{header_retry_after: []string{"60"}})
+
+		f.Do.EXPECT().Do(req).Return(failed, nil).Times(1)
+		res, err := r.Do(req)
+		mocks.CmpError(t, err, nil)
+		if res != failed {
+			t.Errorf("Expected correct response.")
+		}
+	})
+
+	t.Run("cancelled context stops retries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newRetryFixture(ctrl)
+		ctx, cancel := context.WithCancel(f.Ctx)
+		f.Ctx = ctx
+		r := newTestableRetryingHttpDo(f)
+		req := f.request(http.MethodGet, nil)
+
+		f.Do.EXPECT().Do(req).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			cancel()
+			return nil, f.Error
+		}).Times(1)
+		_, err := r.Do(req)
+		mocks.CmpError(t, err, f.Error)
+	})
+}
+
+func TestRetryingHttpDo_Breaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newRetryFixture(ctrl)
+	breaker := NewCircuitBreaker(BreakerPolicy{
+		FailureRatio: 0.5,
+		MinRequests:  1,
+		Window:       time.Minute,
+		CoolDown:     time.Minute,
+	}, f.Logger)
+	r := newTestableRetryingHttpDo(f)
+	r.do = breaker.Wrap(f.Do.Do)
+	req := f.request(http.MethodGet, nil)
+
+	f.Do.EXPECT().Do(req).Return(nil, f.Error).Times(1)
+	f.Logger.EXPECT().Warningf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+	f.Logger.EXPECT().Warningf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), f.Error).Times(1)
+	_, err := r.Do(req)
+	mocks.CmpError(t, err, ErrCircuitOpen)
+	if len(f.Sleeps) != 1 {
+		t.Errorf("Unexpected sleeps: %v", f.Sleeps)
+	}
+}
+
+func TestNewRetryingHttpDo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	do := mock_std.NewMockHttpWrap(ctrl)
+	res := NewRetryingHttpDo(do.Do, RetryPolicy{})
+	if res == nil {
+		t.Errorf("Factory returns nil")
+	}
+}
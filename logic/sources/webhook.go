@@ -0,0 +1,43 @@
+package sources
+
+import (
+	"context"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/utils"
+)
+
+//CacheInvalidator removes value, that is referenced by body of webhook, from cache
+type CacheInvalidator func(ctx context.Context, data []byte) (logic.Response, error)
+
+type invalidateResult struct {
+	Key         string `json:"key"`
+	Invalidated int64  `json:"invalidated"`
+}
+
+//NewCacheInvalidator takes key from body with the same parser, that is used to cache values. Webhook doesn't carry
+//credentials of callers, so value is removed from all partitions of resource, they are taken from index of the key
+//(see IndexKey), keyspace is never scanned. Empty prefix is used by resource with not prefixed keys.
+func NewCacheInvalidator(cache RedisWrap, prefix string, parse DataParser) CacheInvalidator {
+	return func(ctx context.Context, data []byte) (logic.Response, error) {
+		contact, err := parse(data)
+		if err != nil {
+			return nil, logic.NewError(logic.ErrorBadRequest, "failed to get key from request body", err)
+		}
+		keys, err := cache.TakeIndex(IndexKey(prefix, contact.ID))
+		if err != nil {
+			return nil, err
+		}
+		res := &invalidateResult{
+			Key: contact.ID,
+		}
+		if len(keys) > 0 {
+			res.Invalidated, err = cache.DelKeys(keys...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		utils.GetLogger(ctx).Infof("Invalidated %v cached values of '%v'.", res.Invalidated, contact.ID)
+		return newJsonResponse(res)
+	}
+}
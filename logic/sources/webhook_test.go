@@ -0,0 +1,77 @@
+package sources
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/mocks"
+)
+
+func TestNewCacheInvalidator(t *testing.T) {
+
+	t.Run("body without key is a bad request", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		invalidate := NewCacheInvalidator(f.RedisWrap, "contact", logic.ParseContact)
+
+		_, err := invalidate(f.Ctx, []byte(`{"event": "updated"}`))
+		if logic.KindOf(err) != logic.ErrorBadRequest {
+			t.Errorf("Expected bad request. Got: %v", err)
+		}
+	})
+
+	t.Run("keys of all partitions are removed by index", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		invalidate := NewCacheInvalidator(f.RedisWrap, "contact", logic.ParseContact)
+
+		gomock.InOrder(
+			f.RedisWrap.EXPECT().TakeIndex("idx:contact:some-id").Return([]string{"contact:a:some-id", "contact:b:some-id"}, nil).Times(1),
+			f.RedisWrap.EXPECT().DelKeys("contact:a:some-id", "contact:b:some-id").Return(int64(2), nil).Times(1),
+		)
+		f.Logger.EXPECT().Infof(gomock.Any(), int64(2), "some-id").Times(1)
+		res, err := invalidate(f.Ctx, []byte(f.Data))
+		mocks.CmpError(t, err, nil)
+		result := invalidateResult{}
+		readJsonResponse(t, res, &result)
+		if result.Key != "some-id" || result.Invalidated != 2 {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("not cached key is not deleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		invalidate := NewCacheInvalidator(f.RedisWrap, "", logic.ParseContact)
+
+		f.RedisWrap.EXPECT().TakeIndex("idx:some-id").Return([]string{}, nil).Times(1)
+		f.Logger.EXPECT().Infof(gomock.Any(), int64(0), "some-id").Times(1)
+		_, err := invalidate(f.Ctx, []byte(f.Data))
+		mocks.CmpError(t, err, nil)
+	})
+
+	t.Run("redis error is a failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newCacheAdminFixture(ctrl)
+		invalidate := NewCacheInvalidator(f.RedisWrap, "contact", logic.ParseContact)
+
+		f.RedisWrap.EXPECT().TakeIndex("idx:contact:some-id").Return(nil, f.Error).Times(1)
+		_, err := invalidate(f.Ctx, []byte(f.Data))
+		mocks.CmpError(t, err, f.Error)
+
+		f.RedisWrap.EXPECT().TakeIndex("idx:contact:some-id").Return([]string{"a"}, nil).Times(1)
+		f.RedisWrap.EXPECT().DelKeys("a").Return(int64(0), f.Error).Times(1)
+		_, err = invalidate(f.Ctx, []byte(f.Data))
+		mocks.CmpError(t, err, f.Error)
+	})
+}
@@ -0,0 +1,37 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func NewField(key string, value interface{}) Field {
+	return Field{
+		Key:   key,
+		Value: value,
+	}
+}
+
+//fields are copied, so loggers, derived from the same parent, never share underlying array
+func joinFields(parent []Field, fields []Field) []Field {
+	res := make([]Field, 0, len(parent)+len(fields))
+	res = append(res, parent...)
+	return append(res, fields...)
+}
+
+//formatFields returns fields as " key=value key=value" for text output
+func formatFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	b := strings.Builder{}
+	for _, f := range fields {
+		b.WriteString(fmt.Sprintf(" %s=%v", f.Key, f.Value))
+	}
+	return b.String()
+}
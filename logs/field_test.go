@@ -0,0 +1,23 @@
+package logs
+
+import "testing"
+
+func TestFormatFields(t *testing.T) {
+	if res := formatFields(nil); res != "" {
+		t.Errorf("Unexpected result: '%v'", res)
+	}
+	res := formatFields([]Field{NewField("method", "GET"), NewField("status", 200)})
+	if res != " method=GET status=200" {
+		t.Errorf("Unexpected result: '%v'", res)
+	}
+}
+
+func TestJoinFields(t *testing.T) {
+	parent := make([]Field, 1, 4)
+	parent[0] = NewField("a", 1)
+	first := joinFields(parent, []Field{NewField("b", 2)})
+	second := joinFields(parent, []Field{NewField("c", 3)})
+	if first[1].Key != "b" || second[1].Key != "c" || len(parent) != 1 {
+		t.Errorf("Fields are shared: %v, %v", first, second)
+	}
+}
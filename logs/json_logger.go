@@ -0,0 +1,111 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	json_time_key    = "time"
+	json_level_key   = "level"
+	json_message_key = "msg"
+	//fields with reserved keys are written with this prefix, so they never hide or duplicate time, level or message
+	json_reserved_prefix = "field."
+)
+
+//jsonLogger writes one JSON object per line: time, level, message and fields in the order they were added.
+type jsonLogger struct {
+	out      io.Writer
+	lock     *sync.Mutex
+	minLevel Level
+	fields   []Field
+	now      func() time.Time
+}
+
+func writeJsonValue(buf *bytes.Buffer, value interface{}) {
+	//errors are marshalled as empty objects, their message is much more useful
+	err, ok := value.(error)
+	if ok {
+		value = err.Error()
+	}
+	data, mErr := json.Marshal(value)
+	if mErr != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf.Write(data)
+}
+
+func writeJsonField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	writeJsonValue(buf, key)
+	buf.WriteByte(':')
+	writeJsonValue(buf, value)
+}
+
+func jsonFieldKey(key string) string {
+	switch key {
+	case json_time_key, json_level_key, json_message_key:
+		return json_reserved_prefix + key
+	}
+	return key
+}
+
+func (l *jsonLogger) write(level Level, format string, args ...interface{}) {
+	if level < l.minLevel {
+		return
+	}
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	writeJsonField(&buf, json_time_key, l.now().UTC().Format(time.RFC3339Nano))
+	writeJsonField(&buf, json_level_key, level.String())
+	writeJsonField(&buf, json_message_key, fmt.Sprintf(format, args...))
+	for _, f := range l.fields {
+		writeJsonField(&buf, jsonFieldKey(f.Key), f.Value)
+	}
+	buf.WriteString("}\n")
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, _ = l.out.Write(buf.Bytes())
+}
+
+func (l *jsonLogger) Debugf(format string, args ...interface{}) {
+	l.write(LevelDebug, format, args...)
+}
+
+func (l *jsonLogger) Infof(format string, args ...interface{}) {
+	l.write(LevelInfo, format, args...)
+}
+
+func (l *jsonLogger) Warningf(format string, args ...interface{}) {
+	l.write(LevelWarning, format, args...)
+}
+
+func (l *jsonLogger) Errorf(format string, args ...interface{}) {
+	l.write(LevelError, format, args...)
+}
+
+//derived loggers share output and its lock, so lines are never mixed
+func (l *jsonLogger) With(fields ...Field) Logger {
+	return &jsonLogger{
+		out:      l.out,
+		lock:     l.lock,
+		minLevel: l.minLevel,
+		fields:   joinFields(l.fields, fields),
+		now:      l.now,
+	}
+}
+
+func NewJsonLogger(out io.Writer, minLevel Level) Logger {
+	return &jsonLogger{
+		out:      out,
+		lock:     &sync.Mutex{},
+		minLevel: minLevel,
+		now:      time.Now,
+	}
+}
@@ -0,0 +1,143 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type jsonLoggerFixture struct {
+	Out *bytes.Buffer
+	Now time.Time
+}
+
+func newJsonLoggerFixture() *jsonLoggerFixture {
+	return &jsonLoggerFixture{
+		Out: &bytes.Buffer{},
+		Now: time.Date(2019, 11, 20, 10, 0, 0, 0, time.FixedZone("CET", 3600)),
+	}
+}
+
+func (f *jsonLoggerFixture) logger(minLevel Level) Logger {
+	return &jsonLogger{
+		out:      f.Out,
+		lock:     &sync.Mutex{},
+		minLevel: minLevel,
+		now: func() time.Time {
+			return f.Now
+		},
+	}
+}
+
+func (f *jsonLoggerFixture) lines() []string {
+	return strings.Split(strings.TrimSuffix(f.Out.String(), "\n"), "\n")
+}
+
+func TestJsonLogger(t *testing.T) {
+
+	t.Run("one object per line, fields are in order of adding", func(t *testing.T) {
+		f := newJsonLoggerFixture()
+		logger := f.logger(LevelDebug).With(NewField("resource", "contact")).With(NewField("status", 404), NewField("error", errors.New("not found")))
+		logger.Infof("Request to %v.", "/v1/contact")
+
+		expected := `{"time":"2019-11-20T09:00:00Z","level":"info","msg":"Request to /v1/contact.","resource":"contact","status":404,"error":"not found"}` + "\n"
+		if diff := cmp.Diff(expected, f.Out.String()); len(diff) > 0 {
+			t.Errorf("Unexpected output: %v", diff)
+		}
+	})
+
+	t.Run("reserved keys are prefixed", func(t *testing.T) {
+		f := newJsonLoggerFixture()
+		f.logger(LevelDebug).With(NewField("msg", "field"), NewField("level", 1), NewField("time", "now")).Errorf("message")
+
+		parsed := map[string]interface{}{}
+		err := json.Unmarshal(f.Out.Bytes(), &parsed)
+		if err != nil {
+			t.Fatalf("Output is not JSON: %v", err)
+		}
+		expected := map[string]interface{}{
+			"time":        "2019-11-20T09:00:00Z",
+			"level":       "error",
+			"msg":         "message",
+			"field.msg":   "field",
+			"field.level": float64(1),
+			"field.time":  "now",
+		}
+		if diff := cmp.Diff(expected, parsed); len(diff) > 0 {
+			t.Errorf("Unexpected output: %v", diff)
+		}
+	})
+
+	t.Run("messages below level are dropped", func(t *testing.T) {
+		f := newJsonLoggerFixture()
+		logAllLevels(f.logger(LevelWarning))
+
+		levels := []string{}
+		for _, line := range f.lines() {
+			parsed := map[string]string{}
+			err := json.Unmarshal([]byte(line), &parsed)
+			if err != nil {
+				t.Fatalf("Line is not JSON: %v", line)
+			}
+			levels = append(levels, parsed[json_level_key])
+		}
+		if diff := cmp.Diff([]string{"warning", "error"}, levels); len(diff) > 0 {
+			t.Errorf("Unexpected levels: %v", diff)
+		}
+	})
+
+	t.Run("derived loggers don't share fields", func(t *testing.T) {
+		f := newJsonLoggerFixture()
+		parent := f.logger(LevelDebug).With(NewField("a", 1))
+		parent.With(NewField("b", 2)).Infof("b")
+		parent.With(NewField("c", 3)).Infof("c")
+		parent.Infof("parent")
+
+		lines := f.lines()
+		for i, suffix := range []string{`"a":1,"b":2}`, `"a":1,"c":3}`, `"a":1}`} {
+			if !strings.HasSuffix(lines[i], suffix) {
+				t.Errorf("Line %v. Expected suffix: %v. Got: %v", i, suffix, lines[i])
+			}
+		}
+	})
+
+	t.Run("value, that can't be marshalled, is formatted", func(t *testing.T) {
+		f := newJsonLoggerFixture()
+		f.logger(LevelDebug).With(NewField("func", func() {})).Debugf("message")
+		parsed := map[string]interface{}{}
+		err := json.Unmarshal(f.Out.Bytes(), &parsed)
+		if err != nil {
+			t.Fatalf("Output is not JSON: %v", err)
+		}
+		if _, ok := parsed["func"].(string); !ok {
+			t.Errorf("Unexpected value: %v", parsed["func"])
+		}
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]Level{
+		"":        LevelDebug,
+		"debug":   LevelDebug,
+		"INFO":    LevelInfo,
+		"warn":    LevelWarning,
+		"warning": LevelWarning,
+		"error":   LevelError,
+	}
+	for name, expected := range cases {
+		level, err := ParseLevel(name)
+		if err != nil || level != expected {
+			t.Errorf("Name '%v'. Expected: %v. Got: %v, %v", name, expected, level, err)
+		}
+	}
+	_, err := ParseLevel("loud")
+	if err == nil {
+		t.Errorf("Expected error.")
+	}
+}
@@ -0,0 +1,44 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+//ParseLevel accepts names of levels, empty name is debug - everything is logged
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "", "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warning", "warn":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelDebug, fmt.Errorf("unknown log level '%v'", name)
+}
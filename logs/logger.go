@@ -5,4 +5,6 @@ type Logger interface {
 	Infof(format string, args ...interface{})
 	Warningf(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
+	//With returns a logger, that adds fields to every message, original logger is not changed
+	With(fields ...Field) Logger
 }
@@ -21,6 +21,13 @@ func (l *prefixedLogger) Errorf(format string, args ...interface{}) {
 	l.base.Errorf(l.prefix+format, args...)
 }
 
+func (l *prefixedLogger) With(fields ...Field) Logger {
+	return &prefixedLogger{
+		base:   l.base.With(fields...),
+		prefix: l.prefix,
+	}
+}
+
 func NewPrefixedLogger(base Logger, prefix string) Logger {
 	return &prefixedLogger{
 		base:   base,
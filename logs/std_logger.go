@@ -1,6 +1,9 @@
 package logs
 
-import "log"
+import (
+	"fmt"
+	"log"
+)
 
 const (
 	log_debug_prefix   = "[DEBUG] "
@@ -10,24 +13,47 @@ const (
 )
 
 type stdLogger struct {
+	minLevel Level
+	fields   []Field
+}
+
+func (l *stdLogger) printf(level Level, prefix string, format string, args ...interface{}) {
+	if level < l.minLevel {
+		return
+	}
+	//fields are not a part of format - they might contain '%'
+	log.Print(prefix + fmt.Sprintf(format, args...) + formatFields(l.fields))
 }
 
 func (l *stdLogger) Debugf(format string, args ...interface{}) {
-	log.Printf(log_debug_prefix+format, args...)
+	l.printf(LevelDebug, log_debug_prefix, format, args...)
 }
 
 func (l *stdLogger) Infof(format string, args ...interface{}) {
-	log.Printf(log_info_prefix+format, args...)
+	l.printf(LevelInfo, log_info_prefix, format, args...)
 }
 
 func (l *stdLogger) Warningf(format string, args ...interface{}) {
-	log.Printf(log_warning_prefix+format, args...)
+	l.printf(LevelWarning, log_warning_prefix, format, args...)
 }
 
 func (l *stdLogger) Errorf(format string, args ...interface{}) {
-	log.Printf(log_error_prefix+format, args...)
+	l.printf(LevelError, log_error_prefix, format, args...)
+}
+
+func (l *stdLogger) With(fields ...Field) Logger {
+	return &stdLogger{
+		minLevel: l.minLevel,
+		fields:   joinFields(l.fields, fields),
+	}
 }
 
 func NewStdLogger() Logger {
-	return &stdLogger{}
+	return NewLeveledStdLogger(LevelDebug)
+}
+
+func NewLeveledStdLogger(minLevel Level) Logger {
+	return &stdLogger{
+		minLevel: minLevel,
+	}
 }
@@ -0,0 +1,64 @@
+package logs
+
+import "sync/atomic"
+
+//LevelSwitch is a minimal level of messages, that can be changed while application is running
+type LevelSwitch struct {
+	level int32
+}
+
+func (s *LevelSwitch) Set(level Level) {
+	atomic.StoreInt32(&s.level, int32(level))
+}
+
+func (s *LevelSwitch) Get() Level {
+	return Level(atomic.LoadInt32(&s.level))
+}
+
+func NewLevelSwitch(level Level) *LevelSwitch {
+	s := &LevelSwitch{}
+	s.Set(level)
+	return s
+}
+
+//switchedLogger drops messages below level of switch, derived loggers share the switch
+type switchedLogger struct {
+	base  Logger
+	level *LevelSwitch
+}
+
+func (l *switchedLogger) Debugf(format string, args ...interface{}) {
+	if l.level.Get() <= LevelDebug {
+		l.base.Debugf(format, args...)
+	}
+}
+
+func (l *switchedLogger) Infof(format string, args ...interface{}) {
+	if l.level.Get() <= LevelInfo {
+		l.base.Infof(format, args...)
+	}
+}
+
+func (l *switchedLogger) Warningf(format string, args ...interface{}) {
+	if l.level.Get() <= LevelWarning {
+		l.base.Warningf(format, args...)
+	}
+}
+
+func (l *switchedLogger) Errorf(format string, args ...interface{}) {
+	l.base.Errorf(format, args...)
+}
+
+func (l *switchedLogger) With(fields ...Field) Logger {
+	return &switchedLogger{
+		base:  l.base.With(fields...),
+		level: l.level,
+	}
+}
+
+func NewSwitchedLogger(base Logger, level *LevelSwitch) Logger {
+	return &switchedLogger{
+		base:  base,
+		level: level,
+	}
+}
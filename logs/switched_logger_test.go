@@ -0,0 +1,77 @@
+package logs
+
+import (
+	"reflect"
+	"testing"
+)
+
+//recordLogger keeps levels and fields of messages, derived loggers share records
+type recordLogger struct {
+	records *[]string
+	fields  []Field
+}
+
+func (l *recordLogger) record(level Level) {
+	*l.records = append(*l.records, level.String()+formatFields(l.fields))
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) {
+	l.record(LevelDebug)
+}
+
+func (l *recordLogger) Infof(format string, args ...interface{}) {
+	l.record(LevelInfo)
+}
+
+func (l *recordLogger) Warningf(format string, args ...interface{}) {
+	l.record(LevelWarning)
+}
+
+func (l *recordLogger) Errorf(format string, args ...interface{}) {
+	l.record(LevelError)
+}
+
+func (l *recordLogger) With(fields ...Field) Logger {
+	return &recordLogger{
+		records: l.records,
+		fields:  joinFields(l.fields, fields),
+	}
+}
+
+func logAllLevels(l Logger) {
+	l.Debugf("debug")
+	l.Infof("info")
+	l.Warningf("warning")
+	l.Errorf("error")
+}
+
+func TestSwitchedLogger(t *testing.T) {
+
+	t.Run("messages below level are dropped", func(t *testing.T) {
+		cases := map[Level][]string{
+			LevelDebug:   {"debug", "info", "warning", "error"},
+			LevelInfo:    {"info", "warning", "error"},
+			LevelWarning: {"warning", "error"},
+			LevelError:   {"error"},
+		}
+		for level, expected := range cases {
+			records := []string{}
+			logAllLevels(NewSwitchedLogger(&recordLogger{records: &records}, NewLevelSwitch(level)))
+			if !reflect.DeepEqual(records, expected) {
+				t.Errorf("Level %v. Expected: %v. Got: %v", level, expected, records)
+			}
+		}
+	})
+
+	t.Run("derived loggers follow switch", func(t *testing.T) {
+		records := []string{}
+		level := NewLevelSwitch(LevelDebug)
+		logger := NewSwitchedLogger(&recordLogger{records: &records}, level).With(NewField("component", "test"))
+		level.Set(LevelWarning)
+		logAllLevels(logger)
+		expected := []string{"warning component=test", "error component=test"}
+		if !reflect.DeepEqual(records, expected) {
+			t.Errorf("Expected: %v. Got: %v", expected, records)
+		}
+	})
+}
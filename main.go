@@ -1,36 +1,94 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/pprof"
+	"os"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/coldze/test/consts"
 	"github.com/coldze/test/logic"
 	"github.com/coldze/test/logic/handles"
 	"github.com/coldze/test/logic/sources"
 	"github.com/coldze/test/logs"
+	"github.com/coldze/test/metrics"
 	"github.com/coldze/test/utils"
 )
 
 const (
 	HEALTH_CHECK_PATH   = "/ping"
+	READY_PATH          = "/ready"
+	BREAKER_STATUS_PATH = "/breaker"
+	METRICS_PATH        = "/metrics"
+	PPROF_PATH          = "/debug/pprof"
+	CACHE_ENTRIES_PATH  = "/cache/entries"
+	CACHE_PURGE_PATH    = "/cache/purge"
+	WEBHOOKS_PATH       = "/webhooks"
+	CACHE_KEY_VARIABLE  = "key"
 	CONTACT_ID_VARIABLE = "contactid"
-	CONTACT_ROUTE       = "/contact"
 	API_VERSION         = "v1"
 )
 
-func newDataSource(cfg *appCfg) (sources.DataSource, error) {
-	httpDataSource := sources.NewDefaultHttpDataSource(cfg.Api)
-	rWrap, err := sources.NewRedisWrap(cfg.GetRedisOptions())
-	if err != nil {
-		return nil, err
+//resource is an entity of external API with its own data-source
+type resource struct {
+	cfg        *resourceCfg
+	dataSource sources.DataSource
+	//not instrumented data-source, it provides statistics of calls to external API
+	cached sources.DataSource
+	parse  sources.DataParser
+}
+
+func newDataSource(r *resourceCfg, parse sources.DataParser, do sources.HttpDo, rWrap sources.RedisWrap, m *sources.SourceMetrics) sources.DataSource {
+	httpDataSource := sources.NewHttpDataSource(do, r.Api, r.GetEndpoints(), parse)
+	policy := r.GetCachePolicy()
+	cacheSource := sources.NewInstrumentedCacheSource(sources.NewRedisCacheSource(rWrap, policy.StoreTtl(), r.GetCacheHeaders(), parse, r.GetCachePrefix()), m, r.Name)
+	return sources.NewCachedDataSource(httpDataSource, cacheSource, policy, r.GetPartitioner(), parse, sources.NewFlightObserver(m, r.Name))
+}
+
+//newBreakers creates circuit breaker for every resource, so failures of one external API don't stop calls to others.
+//Breakers of previous app are reused, if their resources are still configured (prev is nil, if config of breakers changed).
+func newBreakers(cfg *appCfg, prev map[string]*sources.CircuitBreaker, logger logs.Logger) map[string]*sources.CircuitBreaker {
+	breakers := make(map[string]*sources.CircuitBreaker, len(cfg.Resources))
+	for _, r := range cfg.Resources {
+		if breaker, ok := prev[r.Name]; ok {
+			breakers[r.Name] = breaker
+			continue
+		}
+		breakers[r.Name] = sources.NewCircuitBreaker(cfg.GetBreakerPolicy(), logger.With(logs.NewField("component", "breaker"), logs.NewField("resource", r.Name)))
 	}
-	cacheSource := sources.NewRedisCacheSource(rWrap, cfg.GetCacheTtl())
-	return sources.NewCachedDataSource(httpDataSource, cacheSource), nil
+	return breakers
+}
+
+//retries and connections to external API are shared by all resources, circuit breakers are built per resource
+func newResources(cfg *appCfg, client *http.Client, rWrap sources.RedisWrap, breakers map[string]*sources.CircuitBreaker, m *sources.SourceMetrics) ([]resource, error) {
+	resources := make([]resource, 0, len(cfg.Resources))
+	for i := range cfg.Resources {
+		r := &cfg.Resources[i]
+		parse, err := r.GetKeyParser()
+		if err != nil {
+			return nil, fmt.Errorf("resource '%v': %v", r.Name, err)
+		}
+		breaker, ok := breakers[r.Name]
+		if !ok {
+			return nil, fmt.Errorf("resource '%v': circuit breaker is missing", r.Name)
+		}
+		//breaker counts every attempt and stops retries, once it opens
+		do := sources.NewInstrumentedHttpDo(sources.NewRetryingHttpDo(breaker.Wrap(client.Do), cfg.GetRetryPolicy()), m, r.Name)
+		cached := newDataSource(r, parse, do, rWrap, m)
+		resources = append(resources, resource{
+			cfg:        r,
+			dataSource: sources.NewInstrumentedDataSource(cached, m, r.Name),
+			cached:     cached,
+			parse:      parse,
+		})
+	}
+	return resources, nil
 }
 
 func healthCheck(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +102,23 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	_, _ = ioutil.ReadAll(r.Body)
 }
 
+//newBreakerStatusHandler answers with states of circuit breakers by names of resources
+func newBreakerStatusHandler(breakers map[string]*sources.CircuitBreaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := make(map[string]sources.BreakerStatus, len(breakers))
+		for name, breaker := range breakers {
+			statuses[name] = breaker.Status()
+		}
+		data, err := json.Marshal(statuses)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(consts.HEADER_CONTENT_TYPE, consts.MIME_APPLICATION_JSON)
+		_, _ = w.Write(data)
+	}
+}
+
 //I've put it here, because it is dependent on gorilla/mux, I didn't want to spoil business-logic code with such dependencies
 //This function can be replaced by our own implementation with regex or other manipulations with strings
 //Didn't want to re-implement that logic, as this code is already dependent on gorilla mux, decided to use it's feature
@@ -52,67 +127,228 @@ func NewGetVariableFromRequest(varName string) logic.RequestDataExtractor {
 		vars := mux.Vars(r)
 		value, ok := vars[varName]
 		if !ok {
-			return nil, fmt.Errorf("Failed to find  '%v'", varName)
+			return nil, logic.NewError(logic.ErrorBadRequest, fmt.Sprintf("'%v' is missing", varName), nil)
 		}
 		return []byte(value), nil
 	}
 }
 
-func buildRoutes(dataSource sources.DataSource, logger logs.Logger) http.Handler {
-	getData := NewGetVariableFromRequest(CONTACT_ID_VARIABLE)
-	getHandler := handles.NewGetHandler(handles.NewDefaultLoggerFactory(logs.NewPrefixedLogger(logger, "[GET]")), dataSource, getData)
-	createHandler := handles.NewPostHandler(handles.NewDefaultLoggerFactory(logs.NewPrefixedLogger(logger, "[POST]")), dataSource)
-	updateHandler := handles.NewPutHandler(handles.NewDefaultLoggerFactory(logs.NewPrefixedLogger(logger, "[PUT]")), dataSource)
+//withRouteKey passes key from route to data-source, so it doesn't have to be taken from body
+func withRouteKey(varName string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value, ok := mux.Vars(r)[varName]
+		if !ok {
+			next(w, r)
+			return
+		}
+		next(w, r.WithContext(utils.SetRouteKey(r.Context(), value)))
+	}
+}
+
+//handlers are added only for allowed methods, other methods get 405 from router. If requiredHeader is set, requests
+//without it (API key of a caller) are rejected, so external API is never called on behalf of anonymous callers.
+func addResourceRoutes(sr *mux.Router, res *resource, requiredHeader string, loggerFactory handles.LoggerFactory, instrument func(route string, next http.HandlerFunc) http.HandlerFunc) {
+	r := res.cfg
+	body := &r.RequestBody
+	withKey := func(getData logic.RequestDataExtractor) logic.RequestDataExtractor {
+		if len(requiredHeader) == 0 {
+			return getData
+		}
+		return logic.NewRequiredHeaderExtractor(requiredHeader, getData)
+	}
+	getData := withKey(NewGetVariableFromRequest(CONTACT_ID_VARIABLE))
+	itemRoute := fmt.Sprintf("%s/{%s}", r.Path, CONTACT_ID_VARIABLE)
+	if r.allows(http.MethodGet) {
+		getHandler := handles.NewGetHandler(loggerFactory, res.dataSource, getData)
+		sr.HandleFunc(itemRoute, instrument(itemRoute, getHandler)).Methods(http.MethodGet)
+	}
+	if r.allows(http.MethodPost) {
+		createHandler := handles.NewPostHandler(loggerFactory, res.dataSource, withKey(body.newBodyExtractor(&body.Create)))
+		sr.HandleFunc(r.Path, instrument(r.Path, createHandler)).Methods(http.MethodPost)
+	}
+	if r.allows(http.MethodPut) {
+		updateHandler := handles.NewPutHandler(loggerFactory, res.dataSource, withKey(body.newBodyExtractor(&body.Update)))
+		sr.HandleFunc(r.Path, instrument(r.Path, updateHandler)).Methods(http.MethodPut)
+		sr.HandleFunc(itemRoute, instrument(itemRoute, withRouteKey(CONTACT_ID_VARIABLE, updateHandler))).Methods(http.MethodPut)
+	}
+	if r.allows(http.MethodPatch) {
+		patchHandler := handles.NewPatchHandler(loggerFactory, res.dataSource, withKey(body.newBodyExtractor(&body.Patch)))
+		sr.HandleFunc(r.Path, instrument(r.Path, patchHandler)).Methods(http.MethodPatch)
+		sr.HandleFunc(itemRoute, instrument(itemRoute, withRouteKey(CONTACT_ID_VARIABLE, patchHandler))).Methods(http.MethodPatch)
+	}
+	if r.allows(http.MethodDelete) {
+		deleteHandler := handles.NewDeleteHandler(loggerFactory, res.dataSource, getData)
+		sr.HandleFunc(itemRoute, instrument(itemRoute, deleteHandler)).Methods(http.MethodDelete)
+	}
+}
 
-	router := mux.NewRouter()
+//ops endpoints are served on admin listener, if it's configured
+func addOpsRoutes(router *mux.Router, breakers map[string]*sources.CircuitBreaker, ready http.HandlerFunc, registry *metrics.Registry) {
 	router.Path(HEALTH_CHECK_PATH).HandlerFunc(healthCheck)
-	sr := router.PathPrefix(fmt.Sprintf("/%s", API_VERSION)).Subrouter()
+	router.Path(READY_PATH).HandlerFunc(ready).Methods(http.MethodGet)
+	router.Path(BREAKER_STATUS_PATH).HandlerFunc(newBreakerStatusHandler(breakers)).Methods(http.MethodGet)
+	router.Path(METRICS_PATH).HandlerFunc(registry.Handler()).Methods(http.MethodGet)
+}
+
+//profiling is never exposed on public listener
+func addProfilingRoutes(router *mux.Router) {
+	router.HandleFunc(PPROF_PATH+"/cmdline", pprof.Cmdline)
+	router.HandleFunc(PPROF_PATH+"/profile", pprof.Profile)
+	router.HandleFunc(PPROF_PATH+"/symbol", pprof.Symbol)
+	router.HandleFunc(PPROF_PATH+"/trace", pprof.Trace)
+	router.PathPrefix(PPROF_PATH + "/").HandlerFunc(pprof.Index)
+}
+
+//cache administration is dangerous, it's served only on admin listener.
+//Bodies of admin requests are small, so default limit is used.
+func addCacheAdminRoutes(router *mux.Router, resources []resource, rWrap sources.RedisWrap, handlerMetrics *handles.HandlerMetrics, logger logs.Logger) {
+	loggerFactory := handles.NewDefaultLoggerFactory(logger.With(logs.NewField("component", "cache_admin")))
+	instrument := newInstrumenter(handlerMetrics, loggerFactory)
+	prefixes := map[string]string{}
+	for i := range resources {
+		prefixes[resources[i].cfg.Name] = resources[i].cfg.GetCachePrefix()
+	}
+	admin := sources.NewRedisCacheAdmin(rWrap, prefixes)
+	getKey := NewGetVariableFromRequest(CACHE_KEY_VARIABLE)
+	getBody := logic.NewJsonBodyExtractor(logic.NewBodyLimitExtractor(default_max_body_bytes, logic.GetRequestBodyData))
+	entryRoute := fmt.Sprintf("%s/{%s:.+}", CACHE_ENTRIES_PATH, CACHE_KEY_VARIABLE)
+	router.HandleFunc(entryRoute, instrument(entryRoute, handles.NewCacheInspectHandler(loggerFactory, admin, getKey))).Methods(http.MethodGet)
+	router.HandleFunc(entryRoute, instrument(entryRoute, handles.NewCacheDeleteHandler(loggerFactory, admin, getKey))).Methods(http.MethodDelete)
+	router.HandleFunc(CACHE_PURGE_PATH, instrument(CACHE_PURGE_PATH, handles.NewCachePurgeHandler(loggerFactory, admin, getBody))).Methods(http.MethodPost)
+	for i := range resources {
+		res := &resources[i]
+		if !res.cfg.allows(http.MethodGet) {
+			continue
+		}
+		warm := sources.NewCacheWarmer(res.dataSource, 0)
+		route := fmt.Sprintf("/cache/resources/%s/warm", res.cfg.Name)
+		router.HandleFunc(route, instrument(route, handles.NewCacheWarmHandler(loggerFactory, warm, getBody))).Methods(http.MethodPost)
+	}
+}
 
-	sr.HandleFunc(CONTACT_ROUTE, createHandler).Methods(http.MethodPost)
-	sr.HandleFunc(CONTACT_ROUTE, updateHandler).Methods(http.MethodPut)
-	sr.HandleFunc(fmt.Sprintf("%s/{%s}", CONTACT_ROUTE, CONTACT_ID_VARIABLE), getHandler).Methods(http.MethodGet)
-	return router
+//newInstrumenter counts requests by route and recovers from panics of handlers
+func newInstrumenter(handlerMetrics *handles.HandlerMetrics, loggerFactory handles.LoggerFactory) func(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(route string, next http.HandlerFunc) http.HandlerFunc {
+		return handles.NewInstrumentedHandler(handlerMetrics, route, handles.NewRecoveryHandler(loggerFactory, handlerMetrics, route, next))
+	}
+}
+
+//webhook is served on public listener, so external API can reach it. It's authenticated by signature, not by API key.
+func addWebhookRoute(sr *mux.Router, res *resource, rWrap sources.RedisWrap, loggerFactory handles.LoggerFactory, instrument func(route string, next http.HandlerFunc) http.HandlerFunc) {
+	w := &res.cfg.Webhook
+	invalidate := sources.NewCacheInvalidator(rWrap, res.cfg.GetCachePrefix(), res.parse)
+	route := fmt.Sprintf("%s/%s", WEBHOOKS_PATH, res.cfg.Name)
+	sr.HandleFunc(route, instrument(route, handles.NewWebhookHandler(loggerFactory, invalidate, w.newBodyExtractor()))).Methods(http.MethodPost)
+}
+
+func addApiRoutes(router *mux.Router, resources []resource, rWrap sources.RedisWrap, requiredHeader string, handlerMetrics *handles.HandlerMetrics, logger logs.Logger) {
+	sr := router.PathPrefix(fmt.Sprintf("/%s", API_VERSION)).Subrouter()
+	for i := range resources {
+		loggerFactory := handles.NewDefaultLoggerFactory(logger.With(logs.NewField("resource", resources[i].cfg.Name)))
+		instrumentRoute := newInstrumenter(handlerMetrics, loggerFactory)
+		instrument := func(route string, next http.HandlerFunc) http.HandlerFunc {
+			return instrumentRoute(fmt.Sprintf("/%s%s", API_VERSION, route), next)
+		}
+		addResourceRoutes(sr, &resources[i], requiredHeader, loggerFactory, instrument)
+		if resources[i].cfg.Webhook.IsEnabled() {
+			addWebhookRoute(sr, &resources[i], rWrap, loggerFactory, instrument)
+		}
+	}
 }
 
-func newMainFunc(cfg *appCfg) utils.MainFunc {
-	return func(logger logs.Logger, stop <-chan struct{}) int {
-		dataSource, err := newDataSource(cfg)
+func newMainFunc(cfg *appCfg, level *logs.LevelSwitch, load configLoader) utils.MainFunc {
+	return func(logger logs.Logger, stop <-chan struct{}, reload <-chan struct{}) int {
+		registry := metrics.NewRegistry()
+		rt := &runtime{
+			logger:         logger,
+			level:          level,
+			registry:       registry,
+			sourceMetrics:  sources.NewSourceMetrics(registry),
+			handlerMetrics: handles.NewHandlerMetrics(registry),
+			handler:        &handlerSwitch{},
+			adminHandler:   &handlerSwitch{},
+			stop:           stop,
+		}
+		current, err := rt.newApp(cfg, nil)
 		if err != nil {
-			logger.Errorf("Failed to create data-source. Error: %v", err)
+			logger.Errorf("Failed to start. Error: %v", err)
 			return 1
 		}
+		rt.setRouters(current)
 
-		router := buildRoutes(dataSource, logger)
-
-		bind := cfg.GetBind()
-		srv, err := utils.NewService(bind, router)
+		l, err := rt.listen(cfg, nil, listeners{})
 		if err != nil {
 			logger.Errorf("Failed to start service. Error: %v", err)
 			return 1
 		}
+		//listeners are replaced on reload, all current ones are stopped together
 		defer func() {
-			cErr := srv.Stop()
-			if cErr != nil {
-				logger.Errorf("Failed to stop service: %+v", cErr)
-			}
+			l.stop(logger)
 		}()
-		logger.Infof("Ready. Listening at '%s'", bind)
-		<-stop
-		return 0
+		var watch <-chan struct{}
+		if cfg.GetWatchConfigInterval() > 0 && len(cfg.path) > 0 {
+			watch = utils.WatchFile(cfg.path, cfg.GetWatchConfigInterval(), stop, logger)
+		}
+		logger.Infof("Ready.")
+		for {
+			select {
+			case <-stop:
+				rt.logFlightStats(current)
+				//load balancer needs time to see 503 of /ready, requests are still served meanwhile
+				rt.drain(current.cfg.GetDrainDelay(), l)
+				return 0
+			case err = <-l.api.Failed():
+				logger.Errorf("Service failed. Error: %v", err)
+				return 1
+			case err = <-l.adminFailed():
+				logger.Errorf("Admin service failed. Error: %v", err)
+				return 1
+			case <-reload:
+				current, l = rt.reload(current, l, load)
+			case <-watch:
+				current, l = rt.reload(current, l, load)
+			}
+		}
 	}
 }
 
 func main() {
 	configPath := flag.String("config", "./config.json", "service's configuration in JSON format")
-	redisPwd := flag.String("redispwd", "", "Redis password")
+	redisPwd := flag.String("redispwd", "", "Redis password (deprecated: it's visible in process list, use "+env_prefix+"_REDIS_PASSWORD_FILE)")
 	flag.Parse()
 	logger := logs.NewStdLogger()
 	logger.Infof("Starting...")
-	cfg, err := getConfig(*configPath, *redisPwd)
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	//flags override all other sources, but only if they are set explicitly
+	load := func() (*appCfg, error) {
+		return getConfig(*configPath, setFlags["config"], os.LookupEnv, func(cfg *appCfg) {
+			if setFlags["redispwd"] {
+				cfg.Redis.Password = *redisPwd
+			}
+		})
+	}
+	cfg, err := load()
 	if err != nil {
 		logger.Errorf("Failed to load config. Error: %v", err)
 		return
 	}
-	utils.Run(cfg.GetAppTimeout(), newMainFunc(cfg), logger)
+	level, err := cfg.GetLogLevel()
+	if err != nil {
+		logger.Errorf("Failed to create logger. Error: %v", err)
+		return
+	}
+	levelSwitch := logs.NewLevelSwitch(level)
+	logger, err = cfg.NewLogger(levelSwitch)
+	if err != nil {
+		logs.NewStdLogger().Errorf("Failed to create logger. Error: %v", err)
+		return
+	}
+	if setFlags["redispwd"] {
+		logger.Warningf("Redis password is provided via command line and is visible in process list. Use %v_REDIS_PASSWORD or %v_REDIS_PASSWORD_FILE instead.", env_prefix, env_prefix)
+	}
+	utils.Run(cfg.GetAppTimeout(), newMainFunc(cfg, levelSwitch, load), logger)
 	logger.Infof("Done")
 }
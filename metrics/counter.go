@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"bufio"
+	"sync"
+)
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+//Counter is a monotonically increasing value, one per combination of label values.
+type Counter struct {
+	desc
+	lock   sync.Mutex
+	series map[string]*counterSeries
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	key := c.key(values)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{
+			values: append([]string{}, values...),
+		}
+		c.series[key] = s
+	}
+	s.value += delta
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.writeHeader(w, "counter")
+	keys := make([]string, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedKeys(keys) {
+		s := c.series[k]
+		_, _ = w.WriteString(c.name + c.labelPairs(s.values, "", "") + " " + formatFloat(s.value) + "\n")
+	}
+}
+
+func newCounter(name string, help string, labels []string) *Counter {
+	return &Counter{
+		desc: desc{
+			name:   name,
+			help:   help,
+			labels: labels,
+		},
+		series: map[string]*counterSeries{},
+	}
+}
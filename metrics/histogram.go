@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+//DefaultBuckets are suitable for latencies in seconds - from 5ms to 10s.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+//Histogram counts observations in buckets, one set of buckets per combination of label values.
+type Histogram struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+func (h *Histogram) Observe(value float64, values ...string) {
+	key := h.key(values)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			values: append([]string{}, values...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	//counts are kept per bucket and are accumulated on write
+	i := sort.SearchFloat64s(h.buckets, value)
+	if i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.writeHeader(w, "histogram")
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedKeys(keys) {
+		s := h.series[k]
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			_, _ = w.WriteString(h.name + "_bucket" + h.labelPairs(s.values, "le", formatFloat(bound)) + " " + formatUint(cumulative) + "\n")
+		}
+		_, _ = w.WriteString(h.name + "_bucket" + h.labelPairs(s.values, "le", "+Inf") + " " + formatUint(s.count) + "\n")
+		_, _ = w.WriteString(h.name + "_sum" + h.labelPairs(s.values, "", "") + " " + formatFloat(s.sum) + "\n")
+		_, _ = w.WriteString(h.name + "_count" + h.labelPairs(s.values, "", "") + " " + formatUint(s.count) + "\n")
+	}
+}
+
+func formatUint(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+//buckets are sorted and +Inf is dropped, as it's always added on write
+func newHistogram(name string, help string, buckets []float64, labels []string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b, 1) {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Float64s(sorted)
+	return &Histogram{
+		desc: desc{
+			name:   name,
+			help:   help,
+			labels: labels,
+		},
+		buckets: sorted,
+		series:  map[string]*histogramSeries{},
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+
+	t.Run("counter is written with sorted series", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("cache_operations_total", "Cache operations.", "operation", "result")
+		c.Inc("get", "miss")
+		c.Inc("get", "hit")
+		c.Add(2, "get", "hit")
+		c.Add(-1, "get", "hit")
+
+		buf := bytes.Buffer{}
+		err := r.Write(&buf)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		expected := `# HELP cache_operations_total Cache operations.
+# TYPE cache_operations_total counter
+cache_operations_total{operation="get",result="hit"} 3
+cache_operations_total{operation="get",result="miss"} 1
+`
+		if buf.String() != expected {
+			t.Errorf("Expected:\n%v\nGot:\n%v", expected, buf.String())
+		}
+	})
+
+	t.Run("histogram buckets are cumulative", func(t *testing.T) {
+		r := NewRegistry()
+		h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "method")
+		h.Observe(0.05, "GET")
+		h.Observe(0.1, "GET")
+		h.Observe(0.5, "GET")
+		h.Observe(3, "GET")
+
+		buf := bytes.Buffer{}
+		err := r.Write(&buf)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 2
+latency_seconds_bucket{method="GET",le="1"} 3
+latency_seconds_bucket{method="GET",le="+Inf"} 4
+latency_seconds_sum{method="GET"} 3.65
+latency_seconds_count{method="GET"} 4
+`
+		if buf.String() != expected {
+			t.Errorf("Expected:\n%v\nGot:\n%v", expected, buf.String())
+		}
+	})
+
+	t.Run("label values and help are escaped", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("test_total", "Multi\nline \\ help.", "value")
+		c.Inc("a\"b\\c\nd")
+
+		buf := bytes.Buffer{}
+		_ = r.Write(&buf)
+		expected := `# HELP test_total Multi\nline \\ help.
+# TYPE test_total counter
+test_total{value="a\"b\\c\nd"} 1
+`
+		if buf.String() != expected {
+			t.Errorf("Expected:\n%v\nGot:\n%v", expected, buf.String())
+		}
+	})
+
+	t.Run("wrong number of label values panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected panic.")
+			}
+		}()
+		r := NewRegistry()
+		c := r.NewCounter("test_total", "Test.", "a", "b")
+		c.Inc("a")
+	})
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Test.").Inc()
+	rec := httptest.NewRecorder()
+	r.Handler()(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != content_type {
+		t.Errorf("Unexpected response: %v, %v", rec.Code, rec.Header())
+	}
+	if rec.Body.String() != "# HELP test_total Test.\n# TYPE test_total counter\ntest_total 1\n" {
+		t.Errorf("Unexpected body: %v", rec.Body.String())
+	}
+}
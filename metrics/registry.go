@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	content_type = "text/plain; version=0.0.4; charset=utf-8"
+	//separator can't appear in valid UTF-8 label values
+	label_separator = "\xff"
+)
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+//Registry keeps metrics and writes them in Prometheus text format.
+type Registry struct {
+	lock       sync.Mutex
+	collectors []collector
+}
+
+func (r *Registry) register(c collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	c := newCounter(name, help, labels)
+	r.register(c)
+	return c
+}
+
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := newHistogram(name, help, buckets, labels)
+	r.register(h)
+	return h
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.lock.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.lock.Unlock()
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	return buf.Flush()
+}
+
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", content_type)
+		_ = r.Write(w)
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+//desc is a part, that is common for all metric types: name, help and label names
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic("metric '" + d.name + "' expects " + strconv.Itoa(len(d.labels)) + " label value(s), got " + strconv.Itoa(len(values)))
+	}
+	return strings.Join(values, label_separator)
+}
+
+func (d *desc) writeHeader(w *bufio.Writer, kind string) {
+	_, _ = w.WriteString("# HELP " + d.name + " " + escapeHelp(d.help) + "\n")
+	_, _ = w.WriteString("# TYPE " + d.name + " " + kind + "\n")
+}
+
+//labelPairs formats labels as {a="1",b="2"}, extra pair (for example, histogram's le) is added to the end
+func (d *desc) labelPairs(values []string, extraName string, extraValue string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, d.labels[i]+"=\""+escapeLabel(v)+"\"")
+	}
+	if len(extraName) > 0 {
+		pairs = append(pairs, extraName+"=\""+escapeLabel(extraValue)+"\"")
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(keys []string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+func escapeHelp(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(value)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -5,6 +5,7 @@
 package mock_logs
 
 import (
+	logs "github.com/coldze/test/logs"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -99,3 +100,21 @@ func (mr *MockLoggerMockRecorder) Warningf(arg0 interface{}, arg1 ...interface{}
 	varargs := append([]interface{}{arg0}, arg1...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Warningf", reflect.TypeOf((*MockLogger)(nil).Warningf), varargs...)
 }
+
+// With mocks base method
+func (m *MockLogger) With(arg0 ...logs.Field) logs.Logger {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "With", varargs...)
+	ret0, _ := ret[0].(logs.Logger)
+	return ret0
+}
+
+// With indicates an expected call of With
+func (mr *MockLoggerMockRecorder) With(arg0 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "With", reflect.TypeOf((*MockLogger)(nil).With), arg0...)
+}
@@ -8,6 +8,7 @@ import (
 	logic "github.com/coldze/test/logic"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockCacheSource is a mock of CacheSource interface
@@ -34,44 +35,59 @@ func (m *MockCacheSource) EXPECT() *MockCacheSourceMockRecorder {
 }
 
 // Get mocks base method
-func (m *MockCacheSource) Get(key string) (logic.Response, error) {
+func (m *MockCacheSource) Get(partition, key string) (logic.Response, time.Duration, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", key)
+	ret := m.ctrl.Call(m, "Get", partition, key)
 	ret0, _ := ret[0].(logic.Response)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(time.Duration)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // Get indicates an expected call of Get
-func (mr *MockCacheSourceMockRecorder) Get(key interface{}) *gomock.Call {
+func (mr *MockCacheSourceMockRecorder) Get(partition, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCacheSource)(nil).Get), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCacheSource)(nil).Get), partition, key)
 }
 
 // Insert mocks base method
-func (m *MockCacheSource) Insert(response logic.Response) error {
+func (m *MockCacheSource) Insert(partition, key string, response logic.Response) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Insert", response)
+	ret := m.ctrl.Call(m, "Insert", partition, key, response)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Insert indicates an expected call of Insert
-func (mr *MockCacheSourceMockRecorder) Insert(response interface{}) *gomock.Call {
+func (mr *MockCacheSourceMockRecorder) Insert(partition, key, response interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockCacheSource)(nil).Insert), response)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockCacheSource)(nil).Insert), partition, key, response)
 }
 
 // Remove mocks base method
-func (m *MockCacheSource) Remove(response logic.Response) error {
+func (m *MockCacheSource) Remove(partition string, response logic.Response) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Remove", response)
+	ret := m.ctrl.Call(m, "Remove", partition, response)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Remove indicates an expected call of Remove
-func (mr *MockCacheSourceMockRecorder) Remove(response interface{}) *gomock.Call {
+func (mr *MockCacheSourceMockRecorder) Remove(partition, response interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockCacheSource)(nil).Remove), response)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockCacheSource)(nil).Remove), partition, response)
+}
+
+// RemoveKey mocks base method
+func (m *MockCacheSource) RemoveKey(partition, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveKey", partition, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveKey indicates an expected call of RemoveKey
+func (mr *MockCacheSourceMockRecorder) RemoveKey(partition, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveKey", reflect.TypeOf((*MockCacheSource)(nil).RemoveKey), partition, key)
 }
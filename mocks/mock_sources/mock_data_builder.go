@@ -48,6 +48,20 @@ func (mr *MockDataBuilderMockRecorder) Build() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockDataBuilder)(nil).Build))
 }
 
+// StatusCode mocks base method
+func (m *MockDataBuilder) StatusCode() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatusCode")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// StatusCode indicates an expected call of StatusCode
+func (mr *MockDataBuilderMockRecorder) StatusCode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatusCode", reflect.TypeOf((*MockDataBuilder)(nil).StatusCode))
+}
+
 // Header mocks base method
 func (m *MockDataBuilder) Header() http.Header {
 	m.ctrl.T.Helper()
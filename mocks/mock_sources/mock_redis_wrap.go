@@ -76,6 +76,101 @@ func (mr *MockRedisWrapMockRecorder) Get(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRedisWrap)(nil).Get), key)
 }
 
+// GetWithTtl mocks base method
+func (m *MockRedisWrap) GetWithTtl(key string) (interface{}, time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithTtl", key)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(time.Duration)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetWithTtl indicates an expected call of GetWithTtl
+func (mr *MockRedisWrapMockRecorder) GetWithTtl(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithTtl", reflect.TypeOf((*MockRedisWrap)(nil).GetWithTtl), key)
+}
+
+// Scan mocks base method
+func (m *MockRedisWrap) Scan(cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scan", cursor, pattern, count)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Scan indicates an expected call of Scan
+func (mr *MockRedisWrapMockRecorder) Scan(cursor, pattern, count interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockRedisWrap)(nil).Scan), cursor, pattern, count)
+}
+
+// DelKeys mocks base method
+func (m *MockRedisWrap) DelKeys(keys ...string) (int64, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DelKeys", varargs...)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DelKeys indicates an expected call of DelKeys
+func (mr *MockRedisWrapMockRecorder) DelKeys(keys ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelKeys", reflect.TypeOf((*MockRedisWrap)(nil).DelKeys), keys...)
+}
+
+// SetIndexed mocks base method
+func (m *MockRedisWrap) SetIndexed(key string, data interface{}, ttl time.Duration, index string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIndexed", key, data, ttl, index)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetIndexed indicates an expected call of SetIndexed
+func (mr *MockRedisWrapMockRecorder) SetIndexed(key, data, ttl, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIndexed", reflect.TypeOf((*MockRedisWrap)(nil).SetIndexed), key, data, ttl, index)
+}
+
+// TakeIndex mocks base method
+func (m *MockRedisWrap) TakeIndex(index string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeIndex", index)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeIndex indicates an expected call of TakeIndex
+func (mr *MockRedisWrapMockRecorder) TakeIndex(index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeIndex", reflect.TypeOf((*MockRedisWrap)(nil).TakeIndex), index)
+}
+
+// Ping mocks base method
+func (m *MockRedisWrap) Ping() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping
+func (mr *MockRedisWrapMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRedisWrap)(nil).Ping))
+}
+
 // Close mocks base method
 func (m *MockRedisWrap) Close() error {
 	m.ctrl.T.Helper()
@@ -1,9 +1,12 @@
 package mocks
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func CmpError(t *testing.T, err error, expErr error) {
-	if err != expErr {
+	if err != expErr && !errors.Is(err, expErr) {
 		t.Helper()
 		t.Errorf("Unexpected error: (%T)%v", err, err)
 		t.FailNow()
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coldze/test/logic/handles"
+	"github.com/coldze/test/logic/sources"
+)
+
+const (
+	default_readiness_timeout = 2 * time.Second
+	default_drain_seconds     = 5
+)
+
+type readinessCfg struct {
+	TimeoutMs        int    `json:"timeout_ms"`
+	UpstreamProbeUrl string `json:"upstream_probe_url"`
+	UpstreamRequired bool   `json:"upstream_required"`
+	//how long listeners keep serving after stop, while /ready answers 503 (0 - they're stopped right away)
+	DrainSeconds int `json:"drain_seconds"`
+}
+
+func newRedisCheck(rWrap sources.RedisWrap) handles.DependencyCheck {
+	return func(ctx context.Context) error {
+		_, err := rWrap.Ping()
+		return err
+	}
+}
+
+//any response below 500 means that external API is alive. Probe is a request of the service itself, so it's the only
+//one, that is sent with API key of the service (if it's configured).
+func newUpstreamProbe(client *http.Client, url string, u *upstreamCfg) handles.DependencyCheck {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if len(u.ApiKey) > 0 {
+			req.Header.Set(u.getApiKeyHeader(), u.ApiKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("external API responded with status %v", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+//redis is always required, external API is probed only if probe url is configured
+func newDependencies(r *readinessCfg, u *upstreamCfg, client *http.Client, rWrap sources.RedisWrap) []handles.Dependency {
+	dependencies := []handles.Dependency{
+		{
+			Name:     "redis",
+			Required: true,
+			Check:    newRedisCheck(rWrap),
+		},
+	}
+	if len(r.UpstreamProbeUrl) == 0 {
+		return dependencies
+	}
+	return append(dependencies, handles.Dependency{
+		Name:     "upstream",
+		Required: r.UpstreamRequired,
+		Check:    newUpstreamProbe(client, r.UpstreamProbeUrl, u),
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/coldze/test/logic/handles"
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/metrics"
+	"github.com/coldze/test/utils"
+)
+
+type configLoader func() (*appCfg, error)
+
+//handlerSwitch lets service keep its listener, while routes are replaced on reload
+type handlerSwitch struct {
+	handler atomic.Value
+}
+
+func (h *handlerSwitch) set(handler http.Handler) {
+	h.handler.Store(handler)
+}
+
+//listener might be started before its routes are set, it answers with 503 meanwhile
+func (h *handlerSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := h.handler.Load().(http.Handler)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+//runtime is a part of service, that lives as long as the process: metrics are registered only once and logger keeps
+//its output, only level is switched
+type runtime struct {
+	logger         logs.Logger
+	level          *logs.LevelSwitch
+	registry       *metrics.Registry
+	sourceMetrics  *sources.SourceMetrics
+	handlerMetrics *handles.HandlerMetrics
+	handler        *handlerSwitch
+	adminHandler   *handlerSwitch
+	stop           <-chan struct{}
+	//flights of retired apps by names of resources
+	flights map[string]sources.FlightStats
+}
+
+//app is everything, that is built from config and replaced on reload
+type app struct {
+	cfg    *appCfg
+	client *http.Client
+	rWrap  sources.RedisWrap
+	//circuit breakers by names of resources
+	breakers  map[string]*sources.CircuitBreaker
+	resources []resource
+	router    http.Handler
+	//nil, if admin listener is not configured
+	adminRouter http.Handler
+}
+
+//newApp reuses connections to redis and external API and circuit breakers of previous app, if their configs are the same
+func (rt *runtime) newApp(cfg *appCfg, prev *app) (*app, error) {
+	a := &app{
+		cfg: cfg,
+	}
+	if prev != nil && reflect.DeepEqual(prev.cfg.Upstream, cfg.Upstream) {
+		a.client = prev.client
+	} else {
+		client, err := newUpstreamClient(&cfg.Upstream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create http-client for external API: %v", err)
+		}
+		a.client = client
+	}
+	if prev != nil && reflect.DeepEqual(prev.cfg.Redis, cfg.Redis) {
+		a.rWrap = prev.rWrap
+	} else {
+		rWrap, err := sources.NewRedisWrap(cfg.GetRedisOptions())
+		if err != nil {
+			a.retire(prev)
+			return nil, fmt.Errorf("failed to connect to redis: %v", err)
+		}
+		a.rWrap = rWrap
+	}
+	var prevBreakers map[string]*sources.CircuitBreaker
+	if prev != nil && reflect.DeepEqual(prev.cfg.CircuitBreaker, cfg.CircuitBreaker) {
+		prevBreakers = prev.breakers
+	}
+	a.breakers = newBreakers(cfg, prevBreakers, rt.logger)
+	resources, err := newResources(cfg, a.client, a.rWrap, a.breakers, rt.sourceMetrics)
+	if err != nil {
+		a.retire(prev)
+		return nil, fmt.Errorf("failed to create data-sources: %v", err)
+	}
+	a.resources = resources
+	ready := handles.NewReadyHandler(newDependencies(&cfg.Readiness, &cfg.Upstream, a.client, a.rWrap), cfg.GetReadinessTimeout(), rt.stop)
+	router := mux.NewRouter()
+	addApiRoutes(router, resources, a.rWrap, cfg.Upstream.getRequiredHeader(), rt.handlerMetrics, rt.logger)
+	if cfg.Admin.IsEnabled() {
+		admin := mux.NewRouter()
+		addOpsRoutes(admin, a.breakers, ready, rt.registry)
+		addProfilingRoutes(admin)
+		addCacheAdminRoutes(admin, resources, a.rWrap, rt.handlerMetrics, rt.logger)
+		a.adminRouter = admin
+	} else {
+		addOpsRoutes(router, a.breakers, ready, rt.registry)
+	}
+	a.router = router
+	return a, nil
+}
+
+//retire releases connections, that are not used by next app. Requests, that are still in progress, might use them,
+//so they're closed after app timeout - graceful shutdown doesn't wait for requests longer either.
+func (a *app) retire(next *app) {
+	closeRedis := a.rWrap != nil && (next == nil || next.rWrap != a.rWrap)
+	closeClient := a.client != nil && (next == nil || next.client != a.client)
+	if !closeRedis && !closeClient {
+		return
+	}
+	rWrap := a.rWrap
+	client := a.client
+	time.AfterFunc(a.cfg.GetAppTimeout(), func() {
+		if closeRedis {
+			_ = rWrap.Close()
+		}
+		if closeClient {
+			client.CloseIdleConnections()
+		}
+	})
+}
+
+//collectFlightStats adds flights of app, that is replaced, to totals. Flights, that app finishes after that, are not
+//counted.
+func (rt *runtime) collectFlightStats(a *app) {
+	if rt.flights == nil {
+		rt.flights = map[string]sources.FlightStats{}
+	}
+	for _, res := range a.resources {
+		flights, ok := res.cached.(sources.FlightStatsSource)
+		if !ok {
+			continue
+		}
+		stats := flights.FlightStats()
+		total := rt.flights[res.cfg.Name]
+		total.Flights += stats.Flights
+		total.Coalesced += stats.Coalesced
+		total.Abandoned += stats.Abandoned
+		rt.flights[res.cfg.Name] = total
+	}
+}
+
+//logFlightStats logs totals of all apps since start, including resources, that were removed by reload
+func (rt *runtime) logFlightStats(current *app) {
+	rt.collectFlightStats(current)
+	for name, stats := range rt.flights {
+		rt.logger.Infof("Resource '%v'. Upstream calls: %v, coalesced: %v, abandoned: %v", name, stats.Flights, stats.Coalesced, stats.Abandoned)
+	}
+}
+
+//reload keeps current app and listeners, if new config is invalid or can't be applied. Listener is restarted only if
+//its address changed, in-flight requests of the old one are completed.
+func (rt *runtime) reload(current *app, l listeners, load configLoader) (*app, listeners) {
+	cfg, err := load()
+	if err != nil {
+		rt.logger.Errorf("Failed to reload config, old config is kept. Error: %v", err)
+		return current, l
+	}
+	level, err := cfg.GetLogLevel()
+	if err != nil {
+		rt.logger.Errorf("Failed to reload config, old config is kept. Error: %v", err)
+		return current, l
+	}
+	next, err := rt.newApp(cfg, current)
+	if err != nil {
+		rt.logger.Errorf("Failed to reload config, old config is kept. Error: %v", err)
+		return current, l
+	}
+	if cfg.GetBind() == current.cfg.GetBind() && !reflect.DeepEqual(cfg.Bind, current.cfg.Bind) {
+		//listener can't be replaced without a gap, while address is busy
+		rt.logger.Warningf("Changes of bind.tls are applied only on restart or together with change of address.")
+		cfg.Bind = current.cfg.Bind
+	}
+	if current.adminRouter == nil && next.adminRouter != nil {
+		//admin listener is not started yet, so nothing is served by its routes until reload succeeds
+		rt.adminHandler.set(next.adminRouter)
+	}
+	nextListeners, err := rt.listen(cfg, current.cfg, l)
+	if err != nil {
+		next.retire(current)
+		rt.logger.Errorf("Failed to reload config, old config is kept. Error: %v", err)
+		return current, l
+	}
+	if cfg.Log.Format != current.cfg.Log.Format {
+		rt.logger.Warningf("Log format is changed only on restart.")
+	}
+	rt.level.Set(level)
+	rt.setRouters(next)
+	rt.collectFlightStats(current)
+	current.retire(next)
+	rt.logger.Infof("Config reloaded.")
+	return next, nextListeners
+}
+
+func (rt *runtime) setRouters(a *app) {
+	rt.handler.set(a.router)
+	if a.adminRouter != nil {
+		rt.adminHandler.set(a.adminRouter)
+	}
+}
+func newService(cfg *appCfg, handler http.Handler, logger logs.Logger) (utils.Service, error) {
+	if !cfg.Bind.IsTls() {
+		return utils.NewService(cfg.GetBind(), handler)
+	}
+	opts, err := cfg.Bind.GetTlsOptions()
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewTlsService(cfg.GetBind(), handler, opts, logger.With(logs.NewField("component", "tls")))
+}
+
+func stopService(srv utils.Service, logger logs.Logger) {
+	err := srv.Stop()
+	if err != nil {
+		logger.Errorf("Failed to stop service: %+v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/logic/handles"
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/metrics"
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+	"github.com/coldze/test/mocks/mock_sources"
+)
+
+func TestHandlerSwitch(t *testing.T) {
+
+	t.Run("empty switch is unavailable", func(t *testing.T) {
+		h := &handlerSwitch{}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Unexpected status: %v", rec.Code)
+		}
+	})
+
+	t.Run("last handler serves requests", func(t *testing.T) {
+		h := &handlerSwitch{}
+		for _, status := range []int{http.StatusOK, http.StatusAccepted} {
+			status := status
+			h.set(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if rec.Code != status {
+				t.Errorf("Expected: %v. Got: %v", status, rec.Code)
+			}
+		}
+	})
+}
+
+type reloadFixture struct {
+	Error   error
+	Cfg     *appCfg
+	Logger  *mock_logs.MockLogger
+	Runtime *runtime
+}
+
+func newReloadFixture(ctrl *gomock.Controller) *reloadFixture {
+	logger := mock_logs.NewMockLogger(ctrl)
+	logger.EXPECT().With(gomock.Any()).Return(logger).AnyTimes()
+	registry := metrics.NewRegistry()
+	cfg := defaultConfig()
+	cfg.Resources = []resourceCfg{
+		{
+			Name: "contact",
+			Path: "/contact",
+			Api:  "http://127.0.0.1:1",
+		},
+	}
+	return &reloadFixture{
+		Error:  errors.New("some test error"),
+		Cfg:    cfg,
+		Logger: logger,
+		Runtime: &runtime{
+			logger:         logger,
+			level:          logs.NewLevelSwitch(logs.LevelDebug),
+			registry:       registry,
+			sourceMetrics:  sources.NewSourceMetrics(registry),
+			handlerMetrics: handles.NewHandlerMetrics(registry),
+			handler:        &handlerSwitch{},
+			adminHandler:   &handlerSwitch{},
+			stop:           make(chan struct{}),
+		},
+	}
+}
+
+//current app uses mocked redis, so new apps reuse it, while redis config is the same
+func (f *reloadFixture) currentApp(ctrl *gomock.Controller) *app {
+	client, _ := newUpstreamClient(&f.Cfg.Upstream)
+	return &app{
+		cfg:    f.Cfg,
+		client: client,
+		rWrap:  mock_sources.NewMockRedisWrap(ctrl),
+		breakers: map[string]*sources.CircuitBreaker{
+			f.Cfg.Resources[0].Name: sources.NewCircuitBreaker(f.Cfg.GetBreakerPolicy(), f.Logger),
+		},
+	}
+}
+
+func (f *reloadFixture) copyCfg() *appCfg {
+	cfg := *f.Cfg
+	cfg.Resources = append([]resourceCfg{}, f.Cfg.Resources...)
+	return &cfg
+}
+
+func TestRuntime_NewApp(t *testing.T) {
+
+	t.Run("connections and breaker are reused, if their configs are the same", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		current := f.currentApp(ctrl)
+		cfg := f.copyCfg()
+		cfg.Resources[0].CacheTtlSeconds = 10
+
+		next, err := f.Runtime.newApp(cfg, current)
+		mocks.CmpError(t, err, nil)
+		if next.client != current.client || next.rWrap != current.rWrap || !reflect.DeepEqual(next.breakers, current.breakers) {
+			t.Errorf("Connections are not reused.")
+		}
+		if len(next.resources) != 1 || next.router == nil || next.adminRouter != nil {
+			t.Errorf("Unexpected app: %+v", next)
+		}
+	})
+
+	t.Run("breakers are built per resource and recreated, if their config changes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		current := f.currentApp(ctrl)
+		cfg := f.copyCfg()
+		cfg.Resources = append(cfg.Resources, resourceCfg{
+			Name: "lists",
+			Path: "/lists",
+			Api:  "http://127.0.0.1:1",
+		})
+
+		next, err := f.Runtime.newApp(cfg, current)
+		mocks.CmpError(t, err, nil)
+		if len(next.breakers) != 2 || next.breakers["contact"] != current.breakers["contact"] || next.breakers["lists"] == nil {
+			t.Errorf("Unexpected breakers: %+v", next.breakers)
+		}
+
+		cfg = f.copyCfg()
+		cfg.CircuitBreaker.CoolDownSeconds = 100
+		next, err = f.Runtime.newApp(cfg, current)
+		mocks.CmpError(t, err, nil)
+		if len(next.breakers) != 1 || next.breakers["contact"] == current.breakers["contact"] {
+			t.Errorf("Breaker is not recreated: %+v", next.breakers)
+		}
+	})
+
+	t.Run("admin routes are built, if admin listener is enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		cfg := f.copyCfg()
+		cfg.Admin.Port = 9090
+
+		next, err := f.Runtime.newApp(cfg, f.currentApp(ctrl))
+		mocks.CmpError(t, err, nil)
+		if next.adminRouter == nil {
+			t.Errorf("Admin routes are not built.")
+		}
+	})
+
+	t.Run("failed redis is an error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		cfg := f.copyCfg()
+		cfg.Redis.Address = "127.0.0.1:1"
+
+		next, err := f.Runtime.newApp(cfg, f.currentApp(ctrl))
+		if err == nil || next != nil {
+			t.Errorf("Expected error. Got: %v, %+v", err, next)
+		}
+	})
+}
+
+func TestRuntime_Reload(t *testing.T) {
+
+	t.Run("failed loader keeps current app and listeners", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		current := f.currentApp(ctrl)
+		l := listeners{}
+
+		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
+		next, nextListeners := f.Runtime.reload(current, l, func() (*appCfg, error) {
+			return nil, f.Error
+		})
+		if next != current || nextListeners != l {
+			t.Errorf("Current app is replaced.")
+		}
+	})
+
+	t.Run("config, that can't be applied, keeps current app", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		current := f.currentApp(ctrl)
+		l := listeners{}
+		invalidLevel := f.copyCfg()
+		invalidLevel.Log.Level = "loud"
+		failedRedis := f.copyCfg()
+		failedRedis.Redis.Address = "127.0.0.1:1"
+
+		for _, cfg := range []*appCfg{invalidLevel, failedRedis} {
+			cfg := cfg
+			f.Logger.EXPECT().Errorf(gomock.Any(), gomock.Any()).Times(1)
+			next, nextListeners := f.Runtime.reload(current, l, func() (*appCfg, error) {
+				return cfg, nil
+			})
+			if next != current || nextListeners != l {
+				t.Errorf("Current app is replaced.")
+			}
+		}
+	})
+
+	t.Run("routes are switched, listeners are kept", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		current := f.currentApp(ctrl)
+		l := listeners{}
+		cfg := f.copyCfg()
+		cfg.Log.Level = "error"
+
+		f.Logger.EXPECT().Infof(gomock.Any()).Times(1)
+		next, nextListeners := f.Runtime.reload(current, l, func() (*appCfg, error) {
+			return cfg, nil
+		})
+		if next == current || next.cfg != cfg || nextListeners != l {
+			t.Errorf("Current app is not replaced.")
+		}
+		if f.Runtime.level.Get() != logs.LevelError {
+			t.Errorf("Level is not switched: %v", f.Runtime.level.Get())
+		}
+		rec := httptest.NewRecorder()
+		f.Runtime.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HEALTH_CHECK_PATH, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Unexpected status: %v", rec.Code)
+		}
+	})
+}
+
+type flightStatsSource struct {
+	sources.DataSource
+	stats sources.FlightStats
+}
+
+func (s *flightStatsSource) FlightStats() sources.FlightStats {
+	return s.stats
+}
+
+func TestRuntime_LogFlightStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := newReloadFixture(ctrl)
+	newApp := func(name string, stats sources.FlightStats) *app {
+		return &app{
+			resources: []resource{
+				{cfg: &resourceCfg{Name: name}, cached: &flightStatsSource{stats: stats}},
+			},
+		}
+	}
+	f.Runtime.collectFlightStats(newApp("contact", sources.FlightStats{Flights: 3, Coalesced: 2, Abandoned: 1}))
+	f.Runtime.collectFlightStats(newApp("removed", sources.FlightStats{Flights: 1}))
+
+	f.Logger.EXPECT().Infof(gomock.Any(), "contact", int64(5), int64(2), int64(1)).Times(1)
+	f.Logger.EXPECT().Infof(gomock.Any(), "removed", int64(1), int64(0), int64(0)).Times(1)
+	f.Runtime.logFlightStats(newApp("contact", sources.FlightStats{Flights: 2}))
+}
+
+//failedService is a listener, that has already failed
+type failedService struct {
+	failed chan error
+}
+
+func (s *failedService) Stop() error {
+	return nil
+}
+
+func (s *failedService) Failed() <-chan error {
+	return s.failed
+}
+
+func TestRuntime_Drain(t *testing.T) {
+
+	t.Run("listeners are served until delay passes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		f.Logger.EXPECT().Infof(gomock.Any(), 20*time.Millisecond).Times(1)
+		started := time.Now()
+		f.Runtime.drain(20*time.Millisecond, listeners{api: &failedService{}})
+		if time.Since(started) < 20*time.Millisecond {
+			t.Errorf("Drain is shorter than delay.")
+		}
+		f.Runtime.drain(0, listeners{})
+	})
+
+	t.Run("failed listener ends drain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f := newReloadFixture(ctrl)
+		failed := &failedService{failed: make(chan error, 1)}
+		failed.failed <- f.Error
+		f.Logger.EXPECT().Infof(gomock.Any(), time.Hour).Times(1)
+		f.Logger.EXPECT().Errorf(gomock.Any(), f.Error).Times(1)
+		f.Runtime.drain(time.Hour, listeners{api: &failedService{}, admin: failed})
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/coldze/test/consts"
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/sources"
+)
+
+const (
+	default_resource_name = "contact"
+	default_resource_path = "/contact"
+)
+
+var allMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+var (
+	//name is a part of cache keys (':' separates their parts) and routes
+	resourceNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	//path is a route, so it must not contain variables of router
+	resourcePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+	//routes of service, that are served next to resources
+	reservedPaths = []string{WEBHOOKS_PATH}
+	//name is a prefix of cache keys, so it must differ from namespace of indexes
+	reservedNames = []string{sources.INDEX_NAMESPACE}
+)
+
+//resourceCfg describes an entity of external API, that is served under own path with own cache settings
+type resourceCfg struct {
+	Name                        string         `json:"name"`
+	Path                        string         `json:"path"`
+	Api                         string         `json:"api_url"`
+	Endpoints                   endpointsCfg   `json:"endpoints"`
+	Key                         keyCfg         `json:"key"`
+	Methods                     []string       `json:"methods"`
+	CacheTtlSeconds             int            `json:"cache_ttl_seconds"`
+	StaleWhileRevalidateSeconds int            `json:"stale_while_revalidate_seconds"`
+	StaleIfErrorSeconds         int            `json:"stale_if_error_seconds"`
+	CacheHeaders                []string       `json:"cache_headers"`
+	CachePartitionHeaders       []string       `json:"cache_partition_headers"`
+	RequestBody                 requestBodyCfg `json:"request_body"`
+	Webhook                     webhookCfg     `json:"webhook"`
+	legacy                      bool
+}
+
+func (r *resourceCfg) GetEndpoints() sources.Endpoints {
+	def := sources.DefaultEndpoints()
+	return sources.Endpoints{
+		Get:    r.Endpoints.Get.toEndpoint(def.Get),
+		Create: r.Endpoints.Create.toEndpoint(def.Create),
+		Update: r.Endpoints.Update.toEndpoint(def.Update),
+		Patch:  r.Endpoints.Patch.toEndpoint(def.Patch),
+		Delete: r.Endpoints.Delete.toEndpoint(def.Delete),
+	}
+}
+
+//by default key is taken from top-level contact_id
+func (r *resourceCfg) GetKeyParser() (sources.DataParser, error) {
+	if len(r.Key.Paths) == 0 {
+		return logic.ParseContact, nil
+	}
+	parse, err := logic.NewContactParser(r.Key.Paths, r.Key.Separator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key config: %v", err)
+	}
+	return parse, nil
+}
+
+//by default all methods are allowed
+func (r *resourceCfg) GetMethods() []string {
+	if len(r.Methods) == 0 {
+		return allMethods
+	}
+	return r.Methods
+}
+
+func (r *resourceCfg) allows(method string) bool {
+	for _, m := range r.GetMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resourceCfg) GetCacheTtl() time.Duration {
+	return time.Duration(r.CacheTtlSeconds) * time.Second
+}
+
+func (r *resourceCfg) GetCacheHeaders() []string {
+	if r.CacheHeaders == nil {
+		return []string{consts.HEADER_CONTENT_TYPE}
+	}
+	return r.CacheHeaders
+}
+
+//by default cache is partitioned by API key, so callers with different keys never see data of each other
+func (r *resourceCfg) GetCachePartitionHeaders() []string {
+	if r.CachePartitionHeaders == nil {
+		return []string{consts.HEADER_API_KEY}
+	}
+	return r.CachePartitionHeaders
+}
+
+//values of resources are kept apart by prefix with name of resource
+func (r *resourceCfg) GetPartitioner() sources.Partitioner {
+	partition := sources.NewHeaderPartitioner(r.GetCachePartitionHeaders())
+	if r.legacy {
+		return partition
+	}
+	return sources.NewPrefixedPartitioner(r.Name, partition)
+}
+
+//GetCachePrefix is a part of cache keys, that is common for all partitions of resource
+func (r *resourceCfg) GetCachePrefix() string {
+	if r.legacy {
+		return ""
+	}
+	return r.Name
+}
+
+func (r *resourceCfg) GetCachePolicy() sources.CachePolicy {
+	return sources.CachePolicy{
+		Ttl:                  r.GetCacheTtl(),
+		StaleWhileRevalidate: time.Duration(r.StaleWhileRevalidateSeconds) * time.Second,
+		StaleIfError:         time.Duration(r.StaleIfErrorSeconds) * time.Second,
+	}
+}
+
+//pathsOverlap is true, if one path is the other one or its sub-path, e.g. /contact/x is matched by item route of /contact
+func pathsOverlap(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func (r *resourceCfg) validate() error {
+	if len(r.Name) == 0 {
+		return errors.New("name is required")
+	}
+	if !resourceNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("name must match %v", resourceNamePattern)
+	}
+	for _, reserved := range reservedNames {
+		if r.Name == reserved {
+			return fmt.Errorf("name '%v' is reserved", reserved)
+		}
+	}
+	if !resourcePathPattern.MatchString(r.Path) {
+		return fmt.Errorf("path '%v' must start with '/', must not end with '/' and may contain only letters, digits and '._~-'", r.Path)
+	}
+	for _, reserved := range reservedPaths {
+		if pathsOverlap(r.Path, reserved) {
+			return fmt.Errorf("path '%v' is reserved", reserved)
+		}
+	}
+	if len(r.Api) == 0 {
+		return errors.New("api_url is required")
+	}
+	if r.CacheTtlSeconds < 0 {
+		return errors.New("cache_ttl_seconds must not be negative")
+	}
+	if r.StaleWhileRevalidateSeconds < 0 {
+		return errors.New("stale_while_revalidate_seconds must not be negative")
+	}
+	if r.StaleIfErrorSeconds < 0 {
+		return errors.New("stale_if_error_seconds must not be negative")
+	}
+	for _, m := range r.GetMethods() {
+		ok := false
+		for _, known := range allMethods {
+			ok = ok || m == known
+		}
+		if !ok {
+			return fmt.Errorf("method '%v' is not supported", m)
+		}
+	}
+	_, err := r.GetKeyParser()
+	if err != nil {
+		return err
+	}
+	err = r.Webhook.validate()
+	if err != nil {
+		return err
+	}
+	err = r.RequestBody.loadSchemas()
+	if err != nil {
+		return fmt.Errorf("failed to load schema of request body: %v", err)
+	}
+	return nil
+}
+
+//names are used as cache prefixes and paths as routes, so names have to be unique and paths must not overlap
+func validateResources(resources []resourceCfg) []error {
+	errs := []error{}
+	for i := range resources {
+		r := &resources[i]
+		err := r.validate()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid resource '%v': %v", r.Name, err))
+		}
+		for _, prev := range resources[:i] {
+			if prev.Name == r.Name {
+				errs = append(errs, fmt.Errorf("resource '%v' is defined more than once", r.Name))
+			}
+			if pathsOverlap(prev.Path, r.Path) {
+				errs = append(errs, fmt.Errorf("paths of resources '%v' (%v) and '%v' (%v) overlap", prev.Name, prev.Path, r.Name, r.Path))
+			}
+		}
+	}
+	return errs
+}
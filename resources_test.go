@@ -0,0 +1,277 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+
+	"github.com/coldze/test/logic"
+	"github.com/coldze/test/logic/handles"
+	"github.com/coldze/test/logic/sources"
+	"github.com/coldze/test/logs"
+	"github.com/coldze/test/metrics"
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+	"github.com/coldze/test/mocks/mock_sources"
+)
+
+func newTestResourceCfg(name string, path string) resourceCfg {
+	return resourceCfg{
+		Name: name,
+		Path: path,
+		Api:  "http://localhost/" + name,
+	}
+}
+
+func TestResourceCfg_Validate(t *testing.T) {
+
+	t.Run("valid resource", func(t *testing.T) {
+		for _, r := range []resourceCfg{
+			newTestResourceCfg("contact", "/contact"),
+			newTestResourceCfg("mailing_lists-2", "/v2/mailing-lists.json"),
+		} {
+			err := r.validate()
+			mocks.CmpError(t, err, nil)
+		}
+	})
+
+	t.Run("invalid resource", func(t *testing.T) {
+		cases := map[string]resourceCfg{
+			"empty name":          newTestResourceCfg("", "/contact"),
+			"colon in name":       newTestResourceCfg("contact:v2", "/contact"),
+			"upper case name":     newTestResourceCfg("Contact", "/contact"),
+			"reserved name":       newTestResourceCfg("idx", "/idx"),
+			"relative path":       newTestResourceCfg("contact", "contact"),
+			"trailing slash":      newTestResourceCfg("contact", "/contact/"),
+			"empty segment":       newTestResourceCfg("contact", "/v2//contact"),
+			"variable in path":    newTestResourceCfg("contact", "/{id}"),
+			"reserved path":       newTestResourceCfg("contact", WEBHOOKS_PATH),
+			"under reserved path": newTestResourceCfg("contact", WEBHOOKS_PATH+"/contact"),
+		}
+		for name, r := range cases {
+			err := r.validate()
+			if err == nil {
+				t.Errorf("Case '%v'. Expected error.", name)
+			}
+		}
+		r := newTestResourceCfg("contact", "/contact")
+		r.Api = ""
+		r.Methods = []string{"GET"}
+		if err := r.validate(); err == nil {
+			t.Errorf("Expected error for empty api_url.")
+		}
+		r = newTestResourceCfg("contact", "/contact")
+		r.Methods = []string{"TRACE"}
+		if err := r.validate(); err == nil {
+			t.Errorf("Expected error for unsupported method.")
+		}
+		r = newTestResourceCfg("contact", "/contact")
+		r.Key.Paths = []string{"items["}
+		if err := r.validate(); err == nil {
+			t.Errorf("Expected error for invalid key.")
+		}
+		negative := map[string]func(r *resourceCfg){
+			"cache_ttl_seconds": func(r *resourceCfg) {
+				r.CacheTtlSeconds = -1
+			},
+			"stale_while_revalidate_seconds": func(r *resourceCfg) {
+				r.StaleWhileRevalidateSeconds = -1
+			},
+			"stale_if_error_seconds": func(r *resourceCfg) {
+				r.StaleIfErrorSeconds = -1
+			},
+		}
+		for field, set := range negative {
+			r = newTestResourceCfg("contact", "/contact")
+			set(&r)
+			if err := r.validate(); err == nil || !strings.Contains(err.Error(), field) {
+				t.Errorf("Expected error for negative %v. Got: %v", field, err)
+			}
+		}
+	})
+}
+
+func TestValidateResources(t *testing.T) {
+
+	t.Run("distinct resources are valid", func(t *testing.T) {
+		errs := validateResources([]resourceCfg{
+			newTestResourceCfg("contact", "/contact"),
+			newTestResourceCfg("contacts", "/contacts"),
+			newTestResourceCfg("lists", "/v2/lists"),
+		})
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors: %v", errs)
+		}
+	})
+
+	t.Run("duplicated names and overlapping paths are reported", func(t *testing.T) {
+		errs := validateResources([]resourceCfg{
+			newTestResourceCfg("contact", "/contact"),
+			newTestResourceCfg("contact", "/lists"),
+			newTestResourceCfg("archive", "/contact/archive"),
+			newTestResourceCfg("other", "/contact"),
+		})
+		expected := []string{
+			"resource 'contact' is defined more than once",
+			"paths of resources 'contact' (/contact) and 'archive' (/contact/archive) overlap",
+			"paths of resources 'contact' (/contact) and 'other' (/contact) overlap",
+			"paths of resources 'archive' (/contact/archive) and 'other' (/contact) overlap",
+		}
+		if len(errs) != len(expected) {
+			t.Fatalf("Expected %v errors. Got: %v", len(expected), errs)
+		}
+		for i, err := range errs {
+			if err.Error() != expected[i] {
+				t.Errorf("Expected: %v. Got: %v", expected[i], err)
+			}
+		}
+	})
+
+	t.Run("negative cache windows are reported with other problems", func(t *testing.T) {
+		contact := newTestResourceCfg("contact", "/contact")
+		contact.CacheTtlSeconds = -10
+		lists := newTestResourceCfg("lists", "/lists")
+		lists.StaleIfErrorSeconds = -1
+		errs := validateResources([]resourceCfg{
+			contact,
+			lists,
+			newTestResourceCfg("other", "/lists"),
+		})
+		expected := []string{
+			"invalid resource 'contact': cache_ttl_seconds must not be negative",
+			"invalid resource 'lists': stale_if_error_seconds must not be negative",
+			"paths of resources 'lists' (/lists) and 'other' (/lists) overlap",
+		}
+		if len(errs) != len(expected) {
+			t.Fatalf("Expected %v errors. Got: %v", len(expected), errs)
+		}
+		for i, err := range errs {
+			if err.Error() != expected[i] {
+				t.Errorf("Expected: %v. Got: %v", expected[i], err)
+			}
+		}
+	})
+}
+
+func TestPathsOverlap(t *testing.T) {
+	cases := []struct {
+		a       string
+		b       string
+		overlap bool
+	}{
+		{"/contact", "/contact", true},
+		{"/contact", "/contact/x", true},
+		{"/contact/x", "/contact", true},
+		{"/contact", "/contacts", false},
+		{"/v1/contact", "/contact", false},
+	}
+	for _, c := range cases {
+		if pathsOverlap(c.a, c.b) != c.overlap {
+			t.Errorf("Paths '%v' and '%v'. Expected overlap: %v", c.a, c.b, c.overlap)
+		}
+	}
+}
+
+func TestNewResources(t *testing.T) {
+
+	t.Run("data-source is built for every resource", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cfg := defaultConfig()
+		contact := newTestResourceCfg("contact", "/contact")
+		lists := newTestResourceCfg("lists", "/lists")
+		lists.Key.Paths = []string{"account", "id"}
+		cfg.Resources = []resourceCfg{contact, lists}
+		logger := mock_logs.NewMockLogger(ctrl)
+		logger.EXPECT().With(gomock.Any()).Return(logger).AnyTimes()
+		breakers := newBreakers(cfg, nil, logger)
+
+		resources, err := newResources(cfg, http.DefaultClient, mock_sources.NewMockRedisWrap(ctrl), breakers, sources.NewSourceMetrics(metrics.NewRegistry()))
+		mocks.CmpError(t, err, nil)
+		if len(resources) != 2 {
+			t.Fatalf("Unexpected resources: %+v", resources)
+		}
+		if len(breakers) != 2 || breakers["contact"] == breakers["lists"] {
+			t.Errorf("Circuit breakers are not built per resource: %+v", breakers)
+		}
+		for i, res := range resources {
+			if res.cfg != &cfg.Resources[i] || res.dataSource == nil || res.parse == nil {
+				t.Errorf("Unexpected resource: %+v", res)
+			}
+			if _, ok := res.cached.(sources.FlightStatsSource); !ok {
+				t.Errorf("Resource '%v' doesn't provide flight stats.", res.cfg.Name)
+			}
+		}
+		key, err := resources[1].parse([]byte(`{"account": "acme", "id": 42}`))
+		mocks.CmpError(t, err, nil)
+		if key.ID != "acme:42" {
+			t.Errorf("Unexpected key: %v", key.ID)
+		}
+	})
+
+	t.Run("invalid key config is an error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cfg := defaultConfig()
+		r := newTestResourceCfg("contact", "/contact")
+		r.Key.Paths = []string{"items["}
+		cfg.Resources = []resourceCfg{r}
+		breakers := map[string]*sources.CircuitBreaker{
+			r.Name: sources.NewCircuitBreaker(cfg.GetBreakerPolicy(), mock_logs.NewMockLogger(ctrl)),
+		}
+
+		_, err := newResources(cfg, http.DefaultClient, mock_sources.NewMockRedisWrap(ctrl), breakers, sources.NewSourceMetrics(metrics.NewRegistry()))
+		if err == nil || !strings.Contains(err.Error(), "resource 'contact'") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestAddResourceRoutes(t *testing.T) {
+	cases := []struct {
+		name           string
+		requiredHeader string
+		headers        http.Header
+		expected       int
+	}{
+		{"API key isn't required by default", "", http.Header{}, http.StatusOK},
+		{"missing API key is rejected, if it's required", "autopilotapikey", http.Header{}, http.StatusUnauthorized},
+		{"request with API key is served", "autopilotapikey", http.Header{"Autopilotapikey": {"caller key"}}, http.StatusOK},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cfg := newTestResourceCfg("contact", "/contact")
+			dataSource := mock_sources.NewMockDataSource(ctrl)
+			res := &resource{cfg: &cfg, dataSource: dataSource}
+			router := mux.NewRouter()
+			loggerFactory := handles.NewDefaultLoggerFactory(logs.NewJsonLogger(ioutil.Discard, logs.LevelDebug))
+			addResourceRoutes(router, res, c.requiredHeader, loggerFactory, func(route string, next http.HandlerFunc) http.HandlerFunc {
+				return next
+			})
+
+			if c.expected == http.StatusOK {
+				response, err := logic.NewJsonOkResponse([]byte(`{"contact_id": "42"}`))
+				mocks.CmpError(t, err, nil)
+				dataSource.EXPECT().Get(gomock.Any(), []byte("42")).Return(response, nil).Times(1)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/contact/42", nil)
+			req.Header = c.headers
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != c.expected {
+				t.Errorf("Expected: %v. Got: %v", c.expected, rec.Code)
+			}
+		})
+	}
+}
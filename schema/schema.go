@@ -0,0 +1,198 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	type_object  = "object"
+	type_array   = "array"
+	type_string  = "string"
+	type_number  = "number"
+	type_integer = "integer"
+	type_boolean = "boolean"
+	type_null    = "null"
+)
+
+//types is either a single type or a list of types
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var single string
+	err := json.Unmarshal(data, &single)
+	if err == nil {
+		*t = types{single}
+		return nil
+	}
+	var list []string
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return errors.New("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+//additional is either a boolean or a schema
+type additional struct {
+	allowed bool
+	schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &a.allowed)
+	if err == nil {
+		return nil
+	}
+	a.allowed = true
+	a.schema = &Schema{}
+	return json.Unmarshal(data, a.schema)
+}
+
+//Schema is a subset of JSON Schema (draft 7): type, properties, required, additionalProperties, items, minItems,
+//maxItems, enum, const, minLength, maxLength, pattern, format (email, date-time), minimum, maximum, exclusiveMinimum
+//and exclusiveMaximum. Annotations ($schema, $id, $comment, title, description, default, examples) are ignored.
+//Other keywords (e.g. $ref, allOf, anyOf, oneOf, not, patternProperties) are rejected, so schema is never weaker, than
+//its author expects.
+type Schema struct {
+	Type                 types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                *json.RawMessage   `json:"const"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+
+	pattern    *regexp.Regexp
+	constValue interface{}
+	//keywords, that are neither supported nor annotations, they're reported with path by compile
+	unsupported []string
+}
+
+var annotations = []string{"$schema", "$id", "$comment", "title", "description", "default", "examples"}
+
+//keywords are json names of fields of Schema and annotations
+var keywords = func() map[string]bool {
+	res := map[string]bool{}
+	t := reflect.TypeOf(Schema{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if len(tag) > 0 {
+			res[tag] = true
+		}
+	}
+	for _, a := range annotations {
+		res[a] = true
+	}
+	return res
+}()
+
+//schemaFields has the same fields as Schema, but not its methods, so it's decoded without recursion
+type schemaFields Schema
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, (*schemaFields)(s))
+	if err != nil {
+		return err
+	}
+	raw := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	for keyword := range raw {
+		if !keywords[keyword] {
+			s.unsupported = append(s.unsupported, keyword)
+		}
+	}
+	sort.Strings(s.unsupported)
+	return nil
+}
+
+//compile prepares patterns and constants, so they are not parsed on every validation
+func (s *Schema) compile(path string) error {
+	if len(s.unsupported) > 0 {
+		return fmt.Errorf("%v: unsupported keyword(s) %v", path, strings.Join(s.unsupported, ", "))
+	}
+	for _, t := range s.Type {
+		switch t {
+		case type_object, type_array, type_string, type_number, type_integer, type_boolean, type_null:
+		default:
+			return fmt.Errorf("%v: unknown type '%v'", path, t)
+		}
+	}
+	if len(s.Pattern) > 0 {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%v: invalid pattern: %v", path, err)
+		}
+		s.pattern = pattern
+	}
+	if s.Const != nil {
+		err := json.Unmarshal(*s.Const, &s.constValue)
+		if err != nil {
+			return fmt.Errorf("%v: invalid const: %v", path, err)
+		}
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%v: property '%v' has no schema", path, name)
+		}
+		err := property.compile(path + "." + name)
+		if err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+		err := s.AdditionalProperties.schema.compile(path + ".*")
+		if err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	err := json.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+	err = s.compile(root_path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return s, nil
+}
+
+func Load(filename string) (*Schema, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("'%v': %v", filename, err)
+	}
+	return s, nil
+}
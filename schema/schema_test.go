@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const contactSchema = `{
+  "type": "object",
+  "required": ["contact"],
+  "properties": {
+    "contact": {
+      "type": "object",
+      "required": ["Email"],
+      "additionalProperties": false,
+      "properties": {
+        "FirstName": {"type": "string", "maxLength": 5},
+        "LastName": {"type": "string", "minLength": 1},
+        "Email": {"type": "string", "format": "email"},
+        "Age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+        "Status": {"enum": ["active", "inactive"]},
+        "Kind": {"const": "person"},
+        "Phone": {"type": ["string", "null"], "pattern": "^\\+[0-9]+$"},
+        "Tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+        "custom": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Parse([]byte(contactSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		data     string
+		expected []Violation
+	}{
+		{
+			name: "valid document",
+			data: `{"contact": {"FirstName": "Arthu", "Email": "test@slarty.com", "Age": 42, "Status": "active", "Kind": "person",
+				"Phone": null, "Tags": ["a"], "custom": {"string--Test--Field": "This is a test"}}}`,
+		},
+		{
+			name:     "root of wrong type",
+			data:     `[]`,
+			expected: []Violation{{"$", "must be object, got array"}},
+		},
+		{
+			name:     "required fields",
+			data:     `{"contact": {}}`,
+			expected: []Violation{{"$.contact.Email", "is required"}},
+		},
+		{
+			name: "field violations",
+			data: `{"contact": {"FirstName": "Slarty", "LastName": "", "Email": "not an email", "Age": 1.5, "Status": "gone",
+				"Kind": "robot", "Phone": "123", "Tags": ["a", 1, "c"], "custom": {"a": 1}, "Other": true}}`,
+			expected: []Violation{
+				{"$.contact.Age", "must be integer, got number"},
+				{"$.contact.Email", "must be a valid email"},
+				{"$.contact.FirstName", "must be at most 5 characters long"},
+				{"$.contact.Kind", `must be "person"`},
+				{"$.contact.LastName", "must be at least 1 characters long"},
+				{"$.contact.Other", "is not allowed"},
+				{"$.contact.Phone", "must match pattern '^\\+[0-9]+$'"},
+				{"$.contact.Status", `must be one of ["active","inactive"]`},
+				{"$.contact.Tags", "must have at most 2 items"},
+				{"$.contact.Tags[1]", "must be string, got integer"},
+				{"$.contact.custom.a", "must be string, got integer"},
+			},
+		},
+		{
+			name:     "numbers out of range",
+			data:     `{"contact": {"Email": "test@slarty.com", "Age": 150}}`,
+			expected: []Violation{{"$.contact.Age", "must be < 150"}},
+		},
+	}
+	for _, c := range cases {
+		res, err := s.Validate([]byte(c.data))
+		if err != nil {
+			t.Errorf("Case '%v'. Unexpected error: %v", c.name, err)
+			continue
+		}
+		if !cmp.Equal(res, c.expected) {
+			t.Errorf("Case '%v'. Unexpected violations: %v", c.name, cmp.Diff(c.expected, res))
+		}
+	}
+
+	_, err = s.Validate([]byte("{"))
+	if err == nil {
+		t.Errorf("Expected error for invalid JSON.")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, data := range []string{
+		`[]`,
+		`{"type": "text"}`,
+		`{"type": 1}`,
+		`{"properties": {"a": {"pattern": "("}}}`,
+		`{"items": {"type": ["string", "date"]}}`,
+		`{"additionalProperties": {"type": "date"}}`,
+		`{"$ref": "#/definitions/contact"}`,
+		`{"properties": {"a": {"anyOf": [{"type": "string"}]}}}`,
+		`{"items": {"oneOf": [{"type": "string"}]}}`,
+		`{"additionalProperties": {"not": {"type": "string"}}}`,
+		`{"allOf": [{"type": "object"}], "patternProperties": {"^a": {"type": "string"}}}`,
+	} {
+		_, err := Parse([]byte(data))
+		if err == nil {
+			t.Errorf("Expected error for schema: %v", data)
+		}
+	}
+}
+
+func TestParse_Annotations(t *testing.T) {
+	data := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "contact",
+  "$comment": "comment",
+  "title": "Contact",
+  "description": "contact of external API",
+  "properties": {
+    "Email": {"type": "string", "default": "", "examples": ["a@b.c"]}
+  }
+}`
+	_, err := Parse([]byte(data))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_UnsupportedKeywordPath(t *testing.T) {
+	_, err := Parse([]byte(`{"properties": {"a": {"items": {"oneOf": [], "anyOf": []}}}}`))
+	if err == nil || !strings.Contains(err.Error(), "$.a[]: unsupported keyword(s) anyOf, oneOf") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "contact.json")
+	err = ioutil.WriteFile(filename, []byte(contactSchema), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	s, err := Load(filename)
+	if err != nil || s == nil {
+		t.Errorf("Failed to load schema: %v", err)
+	}
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("Expected error for missing file.")
+	}
+}
+
+func TestContactSchema(t *testing.T) {
+	s, err := Load("../test_data/contact.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	for _, filename := range []string{"../test_data/post.json", "../test_data/put.json"} {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to read '%v': %v", filename, err)
+		}
+		violations, err := s.Validate(data)
+		if err != nil || len(violations) != 0 {
+			t.Errorf("Sample '%v' doesn't match schema: %v, %v", filename, violations, err)
+		}
+	}
+}
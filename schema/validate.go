@@ -0,0 +1,212 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	root_path = "$"
+
+	format_email     = "email"
+	format_date_time = "date-time"
+)
+
+//Violation describes a field, that doesn't match schema. Field is a path like $.contact.Email or $.tags[0].
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(path string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Field:   path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func typeOf(value interface{}) string {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return type_object
+	case []interface{}:
+		return type_array
+	case string:
+		return type_string
+	case float64:
+		if typed == math.Trunc(typed) {
+			return type_integer
+		}
+		return type_number
+	case bool:
+		return type_boolean
+	}
+	return type_null
+}
+
+func matchesType(expected types, actual string) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for _, t := range expected {
+		if t == actual || (t == type_number && actual == type_integer) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validate(s *Schema, path string, value interface{}) {
+	actual := typeOf(value)
+	if !matchesType(s.Type, actual) {
+		v.add(path, "must be %v, got %v", strings.Join(s.Type, " or "), actual)
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		v.add(path, "must be one of %v", enumString(s.Enum))
+	}
+	if s.Const != nil && !reflect.DeepEqual(s.constValue, value) {
+		v.add(path, "must be %s", string(*s.Const))
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(s, path, typed)
+	case []interface{}:
+		v.validateArray(s, path, typed)
+	case string:
+		v.validateString(s, path, typed)
+	case float64:
+		v.validateNumber(s, path, typed)
+	}
+}
+
+func (v *validator) validateObject(s *Schema, path string, value map[string]interface{}) {
+	for _, name := range s.Required {
+		_, ok := value[name]
+		if !ok {
+			v.add(path+"."+name, "is required")
+		}
+	}
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	//sorted, so violations are always in the same order
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		if ok {
+			v.validate(property, path+"."+name, value[name])
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if !s.AdditionalProperties.allowed {
+			v.add(path+"."+name, "is not allowed")
+			continue
+		}
+		if s.AdditionalProperties.schema != nil {
+			v.validate(s.AdditionalProperties.schema, path+"."+name, value[name])
+		}
+	}
+}
+
+func (v *validator) validateArray(s *Schema, path string, value []interface{}) {
+	if s.MinItems != nil && len(value) < *s.MinItems {
+		v.add(path, "must have at least %v items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(value) > *s.MaxItems {
+		v.add(path, "must have at most %v items", *s.MaxItems)
+	}
+	if s.Items == nil {
+		return
+	}
+	for i, item := range value {
+		v.validate(s.Items, fmt.Sprintf("%v[%v]", path, i), item)
+	}
+}
+
+func (v *validator) validateString(s *Schema, path string, value string) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		v.add(path, "must be at least %v characters long", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.add(path, "must be at most %v characters long", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		v.add(path, "must match pattern '%v'", s.Pattern)
+	}
+	if !matchesFormat(s.Format, value) {
+		v.add(path, "must be a valid %v", s.Format)
+	}
+}
+
+func (v *validator) validateNumber(s *Schema, path string, value float64) {
+	if s.Minimum != nil && value < *s.Minimum {
+		v.add(path, "must be >= %v", *s.Minimum)
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		v.add(path, "must be <= %v", *s.Maximum)
+	}
+	if s.ExclusiveMinimum != nil && value <= *s.ExclusiveMinimum {
+		v.add(path, "must be > %v", *s.ExclusiveMinimum)
+	}
+	if s.ExclusiveMaximum != nil && value >= *s.ExclusiveMaximum {
+		v.add(path, "must be < %v", *s.ExclusiveMaximum)
+	}
+}
+
+//unknown formats are not checked, as it's allowed by specification
+func matchesFormat(format string, value string) bool {
+	switch format {
+	case format_email:
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case format_date_time:
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}
+	return true
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumString(enum []interface{}) string {
+	data, err := json.Marshal(enum)
+	if err != nil {
+		return fmt.Sprintf("%v", enum)
+	}
+	return string(data)
+}
+
+//Validate returns violations of schema, document is expected to be a valid JSON
+func (s *Schema) Validate(data []byte) ([]Violation, error) {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	v.validate(s, root_path, value)
+	return v.violations, nil
+}
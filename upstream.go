@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/coldze/test/consts"
+)
+
+const (
+	default_upstream_timeout           = 30 * time.Second
+	default_upstream_dial_timeout      = 5 * time.Second
+	default_upstream_tls_timeout       = 5 * time.Second
+	default_upstream_header_timeout    = 10 * time.Second
+	default_upstream_idle_timeout      = 90 * time.Second
+	default_upstream_max_idle          = 100
+	default_upstream_max_idle_per_host = 10
+)
+
+type upstreamCfg struct {
+	TimeoutMs               int    `json:"timeout_ms"`
+	DialTimeoutMs           int    `json:"dial_timeout_ms"`
+	TlsHandshakeTimeoutMs   int    `json:"tls_handshake_timeout_ms"`
+	ResponseHeaderTimeoutMs int    `json:"response_header_timeout_ms"`
+	IdleConnTimeoutMs       int    `json:"idle_conn_timeout_ms"`
+	MaxIdleConns            int    `json:"max_idle_conns"`
+	MaxIdleConnsPerHost     int    `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost         int    `json:"max_conns_per_host"`
+	ProxyUrl                string `json:"proxy_url"`
+	CaFile                  string `json:"ca_file"`
+	CertFile                string `json:"cert_file"`
+	KeyFile                 string `json:"key_file"`
+	ApiKeyHeader            string `json:"api_key_header"`
+	RequireApiKey           bool   `json:"require_api_key"`
+	ApiKey                  string `json:"api_key"`
+	ApiKeyFile              string `json:"api_key_file"`
+}
+
+//zero values are replaced with defaults - there should be no infinite timeouts
+func msOrDefault(value int, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
+	}
+	return time.Duration(value) * time.Millisecond
+}
+
+func intOrDefault(value int, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+func (u *upstreamCfg) getProxy() (func(*http.Request) (*url.URL, error), error) {
+	if len(u.ProxyUrl) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyUrl, err := url.Parse(u.ProxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %v", err)
+	}
+	return http.ProxyURL(proxyUrl), nil
+}
+
+func (u *upstreamCfg) getTlsConfig() (*tls.Config, error) {
+	tlsCfg := &tls.Config{}
+	if len(u.CaFile) > 0 {
+		ca, err := ioutil.ReadFile(u.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA bundle '%v'", u.CaFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if len(u.CertFile) == 0 && len(u.KeyFile) == 0 {
+		return tlsCfg, nil
+	}
+	if len(u.CertFile) == 0 || len(u.KeyFile) == 0 {
+		return nil, errors.New("both cert_file and key_file are required for client certificate")
+	}
+	cert, err := tls.LoadX509KeyPair(u.CertFile, u.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	tlsCfg.Certificates = []tls.Certificate{cert}
+	return tlsCfg, nil
+}
+
+//getApiKeyHeader is a header with API key of a caller, it's passed to external API as is
+func (u *upstreamCfg) getApiKeyHeader() string {
+	if len(u.ApiKeyHeader) == 0 {
+		return consts.HEADER_API_KEY
+	}
+	return u.ApiKeyHeader
+}
+
+//getRequiredHeader is a header, that API requests must have, empty - nothing is required
+func (u *upstreamCfg) getRequiredHeader() string {
+	if !u.RequireApiKey {
+		return ""
+	}
+	return u.getApiKeyHeader()
+}
+
+func newUpstreamClient(u *upstreamCfg) (*http.Client, error) {
+	proxy, err := u.getProxy()
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := u.getTlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   msOrDefault(u.DialTimeoutMs, default_upstream_dial_timeout),
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   msOrDefault(u.TlsHandshakeTimeoutMs, default_upstream_tls_timeout),
+		ResponseHeaderTimeout: msOrDefault(u.ResponseHeaderTimeoutMs, default_upstream_header_timeout),
+		IdleConnTimeout:       msOrDefault(u.IdleConnTimeoutMs, default_upstream_idle_timeout),
+		MaxIdleConns:          intOrDefault(u.MaxIdleConns, default_upstream_max_idle),
+		MaxIdleConnsPerHost:   intOrDefault(u.MaxIdleConnsPerHost, default_upstream_max_idle_per_host),
+		MaxConnsPerHost:       u.MaxConnsPerHost,
+		ForceAttemptHTTP2:     true,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   msOrDefault(u.TimeoutMs, default_upstream_timeout),
+	}, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coldze/test/mocks"
+)
+
+type upstreamFixture struct {
+	Dir string
+}
+
+func newUpstreamFixture(t *testing.T) (*upstreamFixture, func()) {
+	dir, err := ioutil.TempDir("", "upstream")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	return &upstreamFixture{
+		Dir: dir,
+	}, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func (f *upstreamFixture) writePem(t *testing.T, name string, blockType string, data []byte) string {
+	path := filepath.Join(f.Dir, name)
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write '%v': %v", path, err)
+	}
+	return path
+}
+
+//writeCertificate writes self-signed certificate <name>.pem and its key <name>.key
+func (f *upstreamFixture) writeCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return f.writePem(t, name+".pem", "CERTIFICATE", der), f.writePem(t, name+".key", "EC PRIVATE KEY", keyDer)
+}
+
+func transportOf(t *testing.T, client *http.Client) *http.Transport {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Unexpected transport: %T", client.Transport)
+	}
+	return transport
+}
+
+func TestNewUpstreamClient(t *testing.T) {
+
+	t.Run("defaults are applied to zero values", func(t *testing.T) {
+		client, err := newUpstreamClient(&upstreamCfg{})
+		mocks.CmpError(t, err, nil)
+		transport := transportOf(t, client)
+		if client.Timeout != default_upstream_timeout {
+			t.Errorf("Unexpected timeout: %v", client.Timeout)
+		}
+		if transport.TLSHandshakeTimeout != default_upstream_tls_timeout || transport.ResponseHeaderTimeout != default_upstream_header_timeout ||
+			transport.IdleConnTimeout != default_upstream_idle_timeout {
+			t.Errorf("Unexpected timeouts: %+v", transport)
+		}
+		if transport.MaxIdleConns != default_upstream_max_idle || transport.MaxIdleConnsPerHost != default_upstream_max_idle_per_host ||
+			transport.MaxConnsPerHost != 0 {
+			t.Errorf("Unexpected pooling: %+v", transport)
+		}
+		if !transport.ForceAttemptHTTP2 || transport.Proxy == nil || transport.DialContext == nil {
+			t.Errorf("Unexpected transport: %+v", transport)
+		}
+		if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs != nil || len(transport.TLSClientConfig.Certificates) > 0 {
+			t.Errorf("Unexpected TLS config: %+v", transport.TLSClientConfig)
+		}
+	})
+
+	t.Run("config is applied", func(t *testing.T) {
+		client, err := newUpstreamClient(&upstreamCfg{
+			TimeoutMs:               1000,
+			TlsHandshakeTimeoutMs:   200,
+			ResponseHeaderTimeoutMs: 300,
+			IdleConnTimeoutMs:       400,
+			MaxIdleConns:            5,
+			MaxIdleConnsPerHost:     2,
+			MaxConnsPerHost:         3,
+			ProxyUrl:                "http://proxy.local:3128",
+		})
+		mocks.CmpError(t, err, nil)
+		transport := transportOf(t, client)
+		if client.Timeout != time.Second || transport.TLSHandshakeTimeout != 200*time.Millisecond ||
+			transport.ResponseHeaderTimeout != 300*time.Millisecond || transport.IdleConnTimeout != 400*time.Millisecond {
+			t.Errorf("Unexpected timeouts: %v, %+v", client.Timeout, transport)
+		}
+		if transport.MaxIdleConns != 5 || transport.MaxIdleConnsPerHost != 2 || transport.MaxConnsPerHost != 3 {
+			t.Errorf("Unexpected pooling: %+v", transport)
+		}
+		req := httptest.NewRequest(http.MethodGet, "https://api.local/v1/contact/42", nil)
+		proxy, err := transport.Proxy(req)
+		mocks.CmpError(t, err, nil)
+		if proxy == nil || proxy.String() != "http://proxy.local:3128" {
+			t.Errorf("Unexpected proxy: %v", proxy)
+		}
+	})
+
+	t.Run("invalid proxy url is an error", func(t *testing.T) {
+		for _, proxyUrl := range []string{"://proxy.local", "http://proxy.local:port", "http://%zz"} {
+			_, err := newUpstreamClient(&upstreamCfg{ProxyUrl: proxyUrl})
+			if err == nil {
+				t.Errorf("Proxy '%v'. Expected error.", proxyUrl)
+			}
+		}
+	})
+}
+
+func TestUpstreamCfg_GetTlsConfig(t *testing.T) {
+
+	t.Run("external API is verified with CA bundle", func(t *testing.T) {
+		f, cleanup := newUpstreamFixture(t)
+		defer cleanup()
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client, err := newUpstreamClient(&upstreamCfg{})
+		mocks.CmpError(t, err, nil)
+		_, err = client.Get(server.URL)
+		if err == nil {
+			t.Errorf("Certificate of unknown CA is accepted.")
+		}
+
+		caFile := f.writePem(t, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+		client, err = newUpstreamClient(&upstreamCfg{CaFile: caFile})
+		mocks.CmpError(t, err, nil)
+		resp, err := client.Get(server.URL)
+		mocks.CmpError(t, err, nil)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Unexpected status: %v", resp.StatusCode)
+		}
+	})
+
+	t.Run("client certificate is loaded", func(t *testing.T) {
+		f, cleanup := newUpstreamFixture(t)
+		defer cleanup()
+
+		certFile, keyFile := f.writeCertificate(t, "client")
+		cfg, err := (&upstreamCfg{CertFile: certFile, KeyFile: keyFile}).getTlsConfig()
+		mocks.CmpError(t, err, nil)
+		if len(cfg.Certificates) != 1 {
+			t.Errorf("Unexpected certificates: %v", cfg.Certificates)
+		}
+	})
+
+	t.Run("invalid files are an error", func(t *testing.T) {
+		f, cleanup := newUpstreamFixture(t)
+		defer cleanup()
+
+		certFile, keyFile := f.writeCertificate(t, "client")
+		_, otherKey := f.writeCertificate(t, "other")
+		cases := map[string]upstreamCfg{
+			"missing CA bundle":   {CaFile: filepath.Join(f.Dir, "missing.pem")},
+			"no certs in bundle":  {CaFile: keyFile},
+			"certificate only":    {CertFile: certFile},
+			"key only":            {KeyFile: keyFile},
+			"mismatched key":      {CertFile: certFile, KeyFile: otherKey},
+			"missing certificate": {CertFile: filepath.Join(f.Dir, "missing.pem"), KeyFile: keyFile},
+		}
+		for name, u := range cases {
+			u := u
+			_, err := newUpstreamClient(&u)
+			if err == nil {
+				t.Errorf("Case '%v'. Expected error.", name)
+			}
+		}
+	})
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/coldze/test/logs"
 )
@@ -11,10 +13,25 @@ type loggerKey struct{}
 
 type headerKey struct{}
 
+type routeKey struct{}
+
+type requestIdKey struct{}
+
+type cacheStatusKey struct{}
+
+//cacheStatus is set by data-source, while request is handled, and is read by middleware, when it's done
+type cacheStatus struct {
+	lock  sync.Mutex
+	value string
+}
+
 var (
 	//it is recommended to use structs as keys for values in context - not to overlap with other packages by accident.
-	loggerCtxKey loggerKey
-	headerCtxKey headerKey
+	loggerCtxKey      loggerKey
+	headerCtxKey      headerKey
+	routeCtxKey       routeKey
+	requestIdCtxKey   requestIdKey
+	cacheStatusCtxKey cacheStatusKey
 
 	//global variables are bad, but this one is not that bad - it's not exported outside and is used as a default logger, in case nothing was set in context - to remove checking == nil every single time.
 	defaultLogger logs.Logger
@@ -52,6 +69,92 @@ func GetHeaders(ctx context.Context) http.Header {
 	return headers
 }
 
+//SetRouteKey stores key of a resource, taken from request's route (for example, contact id in /contact/{id})
+func SetRouteKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routeCtxKey, key)
+}
+
+func GetRouteKey(ctx context.Context) string {
+	res := ctx.Value(routeCtxKey)
+	if res == nil {
+		return ""
+	}
+	key, ok := res.(string)
+	if !ok {
+		return ""
+	}
+	return key
+}
+
+func SetRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdCtxKey, id)
+}
+
+func GetRequestId(ctx context.Context) string {
+	res := ctx.Value(requestIdCtxKey)
+	if res == nil {
+		return ""
+	}
+	id, ok := res.(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+//WithCacheStatus prepares context, so cache status of a request can be set by SetCacheStatus later
+func WithCacheStatus(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheStatusCtxKey, &cacheStatus{})
+}
+
+func SetCacheStatus(ctx context.Context, status string) {
+	res, ok := ctx.Value(cacheStatusCtxKey).(*cacheStatus)
+	if !ok {
+		return
+	}
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	res.value = status
+}
+
+func GetCacheStatus(ctx context.Context) string {
+	res, ok := ctx.Value(cacheStatusCtxKey).(*cacheStatus)
+	if !ok {
+		return ""
+	}
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	return res.value
+}
+
+//detachedContext keeps values of parent context (logger, headers), but is never cancelled with it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+//DetachContext is used when work, started on behalf of one request, is shared with other requests and shouldn't be cancelled, when the first one goes away.
+func DetachContext(ctx context.Context) context.Context {
+	return detachedContext{
+		parent: ctx,
+	}
+}
+
 func init() {
 	defaultLogger = logs.NewStdLogger()
 }
@@ -13,12 +13,43 @@ const (
 	shutdown_channel_size = 10
 )
 
-type MainFunc func(logger logs.Logger, stopping <-chan struct{}) int
+//MainFunc gets a signal to channel reload, when configuration has to be reloaded (SIGHUP)
+type MainFunc func(logger logs.Logger, stopping <-chan struct{}, reload <-chan struct{}) int
 
 type gracefulShutdown struct {
 	WaitForShutdown  <-chan struct{}
 	ShutdownComplete chan<- int
 	ReturnCode       <-chan int
+	Reload           <-chan struct{}
+}
+
+//notifyReload forwards SIGHUP to reload channel until shutdown. Signals, that come while previous reload is pending,
+//are merged into it.
+func notifyReload(shutdown <-chan struct{}, logger logs.Logger) <-chan struct{} {
+	reload := make(chan struct{}, 1)
+	hangup := make(chan os.Signal, shutdown_channel_size)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-shutdown:
+				return
+			case sig := <-hangup:
+				logger.Infof("Caught sig: %+v. Reloading.", sig)
+				Notify(reload)
+			}
+		}
+	}()
+	return reload
+}
+
+//Notify sends to channel without blocking, if channel already has a pending notification, new one is dropped
+func Notify(c chan<- struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
 }
 
 func runGracefully(timeout time.Duration, logger logs.Logger) *gracefulShutdown {
@@ -31,12 +62,12 @@ func runGracefully(timeout time.Duration, logger logs.Logger) *gracefulShutdown
 	signal.Notify(gracefulStop, syscall.SIGINT)
 
 	go func() {
-		defer close(shutdown)
 		defer close(gracefulStop)
 		exitCode := 0
 		select {
 		case exitCode = <-shutdownComplete:
 			{
+				close(shutdown)
 				logger.Infof("Business logic completed. Exit code: %+v", exitCode)
 				returnCode <- exitCode
 				return
@@ -44,7 +75,8 @@ func runGracefully(timeout time.Duration, logger logs.Logger) *gracefulShutdown
 
 		case sig := <-gracefulStop:
 			logger.Infof("Caught sig: %+v", sig)
-			shutdown <- struct{}{}
+			//closed channel is seen by every reader, not only by main function (for example, by readiness check)
+			close(shutdown)
 		}
 
 		select {
@@ -61,10 +93,11 @@ func runGracefully(timeout time.Duration, logger logs.Logger) *gracefulShutdown
 		WaitForShutdown:  shutdown,
 		ShutdownComplete: shutdownComplete,
 		ReturnCode:       returnCode,
+		Reload:           notifyReload(shutdown, logger),
 	}
 }
 
-func safeRunAppLogic(appLogic MainFunc, stopChan <-chan struct{}, logger logs.Logger) (res int) {
+func safeRunAppLogic(appLogic MainFunc, stopChan <-chan struct{}, reload <-chan struct{}, logger logs.Logger) (res int) {
 	defer func() {
 		r := recover()
 		if r == nil {
@@ -79,14 +112,14 @@ func safeRunAppLogic(appLogic MainFunc, stopChan <-chan struct{}, logger logs.Lo
 		logger.Errorf("mainFunc failed. Unknown error: %+v. Type: %T", r, r)
 		res = 1
 	}()
-	return appLogic(logger, stopChan)
+	return appLogic(logger, stopChan, reload)
 }
 
 func Run(timeout time.Duration, appLogic MainFunc, logger logs.Logger) {
 
 	graceful := runGracefully(timeout, logger)
 	go func() {
-		graceful.ShutdownComplete <- safeRunAppLogic(appLogic, graceful.WaitForShutdown, logger)
+		graceful.ShutdownComplete <- safeRunAppLogic(appLogic, graceful.WaitForShutdown, graceful.Reload, logger)
 	}()
 	exitCode := <-graceful.ReturnCode
 	logger.Infof("Exiting application. Code: %+v", exitCode)
@@ -4,18 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
+
+	"github.com/coldze/test/logs"
 )
 
 type serviceImpl struct {
-	srv     *http.Server
+	servers []*http.Server
 	lock    sync.RWMutex
 	stopped bool
+	failed  chan error
 }
 
 type Service interface {
 	Stop() error
+	//Failed gets an error, if service stopped serving not because of Stop
+	Failed() <-chan error
 }
 
 func (s *serviceImpl) Stop() error {
@@ -25,11 +31,17 @@ func (s *serviceImpl) Stop() error {
 		return errors.New("already stopped")
 	}
 	s.stopped = true
-	err := s.srv.Shutdown(context.Background())
-	if err == nil {
-		return nil
+	for _, srv := range s.servers {
+		err := srv.Shutdown(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to stop http-service: %v", err)
+		}
 	}
-	return fmt.Errorf("failed to stop http-service: %v", err)
+	return nil
+}
+
+func (s *serviceImpl) Failed() <-chan error {
+	return s.failed
 }
 
 func (s *serviceImpl) isStopped() bool {
@@ -38,24 +50,93 @@ func (s *serviceImpl) isStopped() bool {
 	return s.stopped
 }
 
-func NewService(bindAddress string, handler http.Handler) (Service, error) {
-	s := &serviceImpl{
-		srv: &http.Server{
-			Addr:    bindAddress,
-			Handler: handler,
-		},
-	}
-
+//serve is called when all addresses are bound
+func (s *serviceImpl) serve(srv *http.Server, serve func() error) {
 	go func() {
-		err := s.srv.ListenAndServe()
-		if err == nil {
+		err := serve()
+		if err == nil || err == http.ErrServerClosed {
 			return
 		}
 		if s.isStopped() {
 			return
 		}
-		panic(fmt.Errorf("http-service failed to listen: %v", err))
+		s.failed <- fmt.Errorf("http-service failed to serve at '%v': %v", srv.Addr, err)
 	}()
+}
+
+//certificate is taken from TLSConfig, HTTP/2 is configured by ServeTLS
+func (s *serviceImpl) serveTls(srv *http.Server, listener net.Listener) {
+	s.serve(srv, func() error {
+		return srv.ServeTLS(listener, "", "")
+	})
+}
+
+func newServiceImpl(servers ...*http.Server) *serviceImpl {
+	return &serviceImpl{
+		servers: servers,
+		failed:  make(chan error, len(servers)),
+	}
+}
+
+func listen(bindAddress string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", bindAddress)
+	if err != nil {
+		return nil, fmt.Errorf("http-service failed to listen: %v", err)
+	}
+	return listener, nil
+}
+
+//NewService binds address before returning, so busy port is reported right away, not from background goroutine
+func NewService(bindAddress string, handler http.Handler) (Service, error) {
+	listener, err := listen(bindAddress)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Addr:    bindAddress,
+		Handler: handler,
+	}
+	s := newServiceImpl(srv)
+	s.serve(srv, func() error {
+		return srv.Serve(listener)
+	})
+	return s, nil
+}
 
+//NewTlsService terminates TLS and serves both HTTP/2 and HTTP/1.1. If redirect address is set, plain http requests to
+//it are redirected to https.
+func NewTlsService(bindAddress string, handler http.Handler, opts TlsOptions, logger logs.Logger) (Service, error) {
+	tlsCfg, err := opts.newConfig(logger)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := listen(bindAddress)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Addr:      bindAddress,
+		Handler:   handler,
+		TLSConfig: tlsCfg,
+	}
+	if len(opts.RedirectAddress) == 0 {
+		s := newServiceImpl(srv)
+		s.serveTls(srv, listener)
+		return s, nil
+	}
+	redirectListener, err := listen(opts.RedirectAddress)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	redirect := &http.Server{
+		Addr:    opts.RedirectAddress,
+		Handler: NewHttpsRedirectHandler(bindAddress),
+	}
+	s := newServiceImpl(srv, redirect)
+	s.serveTls(srv, listener)
+	s.serve(redirect, func() error {
+		return redirect.Serve(redirectListener)
+	})
 	return s, nil
 }
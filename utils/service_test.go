@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	test_service_wait = time.Second
+)
+
+func TestNewService(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("requests are served until stop", func(t *testing.T) {
+		address := freeAddress(t)
+		s, err := NewService(address, ok)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		client := &http.Client{Timeout: time.Second}
+		resp, err := get(client, "http://"+address+"/ping")
+		if err != nil || resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Unexpected response: %v, %v", resp, err)
+		}
+
+		err = s.Stop()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		_, err = get(client, "http://"+address+"/ping")
+		if err == nil {
+			t.Errorf("Stopped service serves requests.")
+		}
+		if s.Stop() == nil {
+			t.Errorf("Expected error of second stop.")
+		}
+		select {
+		case err := <-s.Failed():
+			t.Errorf("Stop is reported as failure: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+
+	t.Run("port already in use is reported right away", func(t *testing.T) {
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		defer func() {
+			_ = busy.Close()
+		}()
+
+		s, err := NewService(busy.Addr().String(), ok)
+		if err == nil || s != nil {
+			t.Errorf("Expected error. Got: %v, %v", s, err)
+		}
+	})
+}
+
+func TestServiceImpl_Failed(t *testing.T) {
+
+	t.Run("serve failure is reported", func(t *testing.T) {
+		srv := &http.Server{Addr: "127.0.0.1:1"}
+		s := newServiceImpl(srv)
+		s.serve(srv, func() error {
+			return errors.New("some test error")
+		})
+
+		select {
+		case err := <-s.Failed():
+			if err == nil || !strings.Contains(err.Error(), "some test error") || !strings.Contains(err.Error(), srv.Addr) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		case <-time.After(test_service_wait):
+			t.Errorf("Failure is not reported.")
+		}
+	})
+
+	t.Run("closed server and stopped service are not failures", func(t *testing.T) {
+		srv := &http.Server{Addr: "127.0.0.1:1"}
+		s := newServiceImpl(srv)
+		served := make(chan struct{}, 2)
+		s.serve(srv, func() error {
+			defer func() {
+				served <- struct{}{}
+			}()
+			return http.ErrServerClosed
+		})
+		err := s.Stop()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		s.serve(srv, func() error {
+			defer func() {
+				served <- struct{}{}
+			}()
+			return errors.New("some test error")
+		})
+
+		for i := 0; i < 2; i++ {
+			select {
+			case <-served:
+			case <-time.After(test_service_wait):
+				t.Fatalf("Serve is not called.")
+			}
+		}
+		select {
+		case err := <-s.Failed():
+			t.Errorf("Unexpected failure: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
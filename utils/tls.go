@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coldze/test/logs"
+)
+
+const (
+	default_cert_check_interval = 10 * time.Second
+)
+
+//TlsOptions describes TLS of service. Client certificates are verified only if ClientCaFile is set.
+type TlsOptions struct {
+	CertFile          string
+	KeyFile           string
+	MinVersion        uint16
+	ClientCaFile      string
+	RequireClientCert bool
+	//how often certificate files are checked for changes, zero - default
+	CertCheckInterval time.Duration
+	//plain http address, that redirects to https, empty - disabled
+	RedirectAddress string
+}
+
+func (o *TlsOptions) newConfig(logger logs.Logger) (*tls.Config, error) {
+	interval := o.CertCheckInterval
+	if interval <= 0 {
+		interval = default_cert_check_interval
+	}
+	certs, err := newCertificateReloader(o.CertFile, o.KeyFile, interval, logger)
+	if err != nil {
+		return nil, err
+	}
+	minVersion := o.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	cfg := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: certs.GetCertificate,
+		//HTTP/2 is preferred, HTTP/1.1 is kept for old clients
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if len(o.ClientCaFile) == 0 {
+		return cfg, nil
+	}
+	ca, err := ioutil.ReadFile(o.ClientCaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle '%v'", o.ClientCaFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if o.RequireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+//ParseTlsVersion accepts versions like "1.2", empty version is default (1.2)
+func ParseTlsVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version '%v'", version)
+}
+
+//certificateReloader checks files of certificate during handshakes, but not more often than interval, so renewed
+//certificate is used without restart. If new files can't be loaded, previous certificate is kept.
+type certificateReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	logger    logs.Logger
+	now       func() time.Time
+	lock      sync.Mutex
+	cert      *tls.Certificate
+	versions  [2]fileVersion
+	lastCheck time.Time
+}
+
+func (c *certificateReloader) load() error {
+	versions := [2]fileVersion{statFile(c.certFile), statFile(c.keyFile)}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %v", err)
+	}
+	c.cert = &cert
+	c.versions = versions
+	return nil
+}
+
+func (c *certificateReloader) changed() bool {
+	return !statFile(c.certFile).equal(c.versions[0]) || !statFile(c.keyFile).equal(c.versions[1])
+}
+
+func (c *certificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	if now.Sub(c.lastCheck) < c.interval {
+		return c.cert, nil
+	}
+	c.lastCheck = now
+	if !c.changed() {
+		return c.cert, nil
+	}
+	err := c.load()
+	if err != nil {
+		c.logger.Errorf("Failed to reload TLS certificate, previous one is used. Error: %v", err)
+		return c.cert, nil
+	}
+	c.logger.Infof("TLS certificate reloaded from '%v'.", c.certFile)
+	return c.cert, nil
+}
+
+func newCertificateReloader(certFile string, keyFile string, interval time.Duration, logger logs.Logger) (*certificateReloader, error) {
+	c := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.lastCheck = c.now()
+	return c, nil
+}
+
+//NewHttpsRedirectHandler redirects to the same host and path at port of https-service
+func NewHttpsRedirectHandler(httpsAddress string) http.Handler {
+	_, port, err := net.SplitHostPort(httpsAddress)
+	if err != nil || port == "443" {
+		port = ""
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if len(port) > 0 {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,471 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/mocks"
+	"github.com/coldze/test/mocks/mock_logs"
+)
+
+const (
+	test_cert_check_interval = time.Minute
+)
+
+//testCa issues certificates for 127.0.0.1, that are valid both for servers and clients
+type testCa struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	dir    string
+	serial int64
+}
+
+func newTestCa(t *testing.T) (*testCa, func()) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	ca := &testCa{
+		dir: dir,
+	}
+	ca.key = ca.newKey(t)
+	template := ca.template("test CA")
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &ca.key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	ca.cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA: %v", err)
+	}
+	ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func (ca *testCa) newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func (ca *testCa) template(commonName string) *x509.Certificate {
+	ca.serial++
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+}
+
+func (ca *testCa) write(t *testing.T, name string, blockType string, data []byte) string {
+	path := filepath.Join(ca.dir, name)
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write '%v': %v", path, err)
+	}
+	return path
+}
+
+func (ca *testCa) Path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+//Issue writes <name>.pem and <name>.key and returns their paths
+func (ca *testCa) Issue(t *testing.T, name string) (string, string) {
+	key := ca.newKey(t)
+	template := ca.template(name)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return ca.write(t, name+".pem", "CERTIFICATE", der), ca.write(t, name+".key", "EC PRIVATE KEY", keyDer)
+}
+
+func (ca *testCa) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func commonNameOf(t *testing.T, cert *tls.Certificate) string {
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestParseTlsVersion(t *testing.T) {
+	cases := map[string]uint16{
+		"":    0,
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+	for version, expected := range cases {
+		res, err := ParseTlsVersion(version)
+		mocks.CmpError(t, err, nil)
+		if res != expected {
+			t.Errorf("Version '%v'. Expected: %v. Got: %v", version, expected, res)
+		}
+	}
+	for _, version := range []string{"1", "1.4", "TLS1.2", "ssl3"} {
+		_, err := ParseTlsVersion(version)
+		if err == nil {
+			t.Errorf("Version '%v'. Expected error.", version)
+		}
+	}
+}
+
+func TestTlsOptions_NewConfig(t *testing.T) {
+
+	t.Run("defaults", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		certFile, keyFile := ca.Issue(t, "server")
+		opts := TlsOptions{CertFile: certFile, KeyFile: keyFile}
+		cfg, err := opts.newConfig(mock_logs.NewMockLogger(ctrl))
+		mocks.CmpError(t, err, nil)
+		if cfg.MinVersion != tls.VersionTLS12 || cfg.ClientAuth != tls.NoClientCert || cfg.ClientCAs != nil {
+			t.Errorf("Unexpected config: %+v", cfg)
+		}
+		if len(cfg.NextProtos) != 2 || cfg.NextProtos[0] != "h2" {
+			t.Errorf("HTTP/2 is not preferred: %v", cfg.NextProtos)
+		}
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+		mocks.CmpError(t, err, nil)
+		if commonNameOf(t, cert) != "server" {
+			t.Errorf("Unexpected certificate.")
+		}
+	})
+
+	t.Run("client certificates are verified with CA", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		certFile, keyFile := ca.Issue(t, "server")
+		cases := []struct {
+			opts     TlsOptions
+			expected tls.ClientAuthType
+		}{
+			{TlsOptions{RequireClientCert: true}, tls.NoClientCert},
+			{TlsOptions{ClientCaFile: ca.Path("ca.pem")}, tls.VerifyClientCertIfGiven},
+			{TlsOptions{ClientCaFile: ca.Path("ca.pem"), RequireClientCert: true}, tls.RequireAndVerifyClientCert},
+		}
+		for _, c := range cases {
+			c.opts.CertFile = certFile
+			c.opts.KeyFile = keyFile
+			c.opts.MinVersion = tls.VersionTLS13
+			cfg, err := c.opts.newConfig(mock_logs.NewMockLogger(ctrl))
+			mocks.CmpError(t, err, nil)
+			if cfg.ClientAuth != c.expected || cfg.MinVersion != tls.VersionTLS13 {
+				t.Errorf("Options %+v. Expected client auth: %v. Got: %+v", c.opts, c.expected, cfg)
+			}
+			if (cfg.ClientCAs != nil) != (len(c.opts.ClientCaFile) > 0) {
+				t.Errorf("Options %+v. Unexpected client CAs.", c.opts)
+			}
+		}
+	})
+
+	t.Run("invalid files are an error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		certFile, keyFile := ca.Issue(t, "server")
+		_, otherKey := ca.Issue(t, "other")
+		cases := map[string]TlsOptions{
+			"missing certificate": {CertFile: ca.Path("missing.pem"), KeyFile: keyFile},
+			"mismatched key":      {CertFile: certFile, KeyFile: otherKey},
+			"missing client CA":   {CertFile: certFile, KeyFile: keyFile, ClientCaFile: ca.Path("missing.pem")},
+			"no certs in CA":      {CertFile: certFile, KeyFile: keyFile, ClientCaFile: keyFile},
+		}
+		for name, opts := range cases {
+			_, err := opts.newConfig(mock_logs.NewMockLogger(ctrl))
+			if err == nil {
+				t.Errorf("Case '%v'. Expected error.", name)
+			}
+		}
+	})
+}
+
+type reloaderFixture struct {
+	Ca       *testCa
+	Logger   *mock_logs.MockLogger
+	Now      time.Time
+	Reloader *certificateReloader
+}
+
+func newReloaderFixture(t *testing.T, ctrl *gomock.Controller) (*reloaderFixture, func()) {
+	ca, cleanup := newTestCa(t)
+	f := &reloaderFixture{
+		Ca:     ca,
+		Logger: mock_logs.NewMockLogger(ctrl),
+		Now:    time.Now(),
+	}
+	certFile, keyFile := ca.Issue(t, "first")
+	reloader, err := newCertificateReloader(certFile, keyFile, test_cert_check_interval, f.Logger)
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create reloader: %v", err)
+	}
+	reloader.now = func() time.Time {
+		return f.Now
+	}
+	reloader.lastCheck = f.Now
+	f.Reloader = reloader
+	return f, cleanup
+}
+
+//replace issues certificate with new name to the same files, modification time is moved, so change is noticed
+//regardless of precision of file system
+func (f *reloaderFixture) replace(t *testing.T, name string) {
+	certFile, keyFile := f.Ca.Issue(t, name)
+	for src, dst := range map[string]string{certFile: f.Reloader.certFile, keyFile: f.Reloader.keyFile} {
+		err := os.Rename(src, dst)
+		if err != nil {
+			t.Fatalf("Failed to replace '%v': %v", dst, err)
+		}
+		modTime := time.Now().Add(time.Hour)
+		err = os.Chtimes(dst, modTime, modTime)
+		if err != nil {
+			t.Fatalf("Failed to touch '%v': %v", dst, err)
+		}
+	}
+}
+
+func (f *reloaderFixture) expect(t *testing.T, commonName string) {
+	cert, err := f.Reloader.GetCertificate(&tls.ClientHelloInfo{})
+	mocks.CmpError(t, err, nil)
+	if actual := commonNameOf(t, cert); actual != commonName {
+		t.Errorf("Expected certificate: %v. Got: %v", commonName, actual)
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+
+	t.Run("files are not checked more often than interval", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		f, cleanup := newReloaderFixture(t, ctrl)
+		defer cleanup()
+
+		f.replace(t, "second")
+		f.Now = f.Now.Add(test_cert_check_interval / 2)
+		f.expect(t, "first")
+	})
+
+	t.Run("changed files are reloaded after interval", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		f, cleanup := newReloaderFixture(t, ctrl)
+		defer cleanup()
+
+		f.replace(t, "second")
+		f.Now = f.Now.Add(test_cert_check_interval)
+		f.Logger.EXPECT().Infof(gomock.Any(), f.Reloader.certFile).Times(1)
+		f.expect(t, "second")
+		//files are the same, nothing is reloaded
+		f.Now = f.Now.Add(test_cert_check_interval)
+		f.expect(t, "second")
+	})
+
+	t.Run("previous certificate is kept, if new one can't be loaded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		f, cleanup := newReloaderFixture(t, ctrl)
+		defer cleanup()
+
+		err := ioutil.WriteFile(f.Reloader.keyFile, []byte("broken"), 0600)
+		if err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+		f.Now = f.Now.Add(test_cert_check_interval)
+		f.Logger.EXPECT().Errorf(gomock.Any(), gomock.Any()).Times(1)
+		f.expect(t, "first")
+	})
+}
+
+func TestNewHttpsRedirectHandler(t *testing.T) {
+	cases := []struct {
+		httpsAddress string
+		target       string
+		expected     string
+	}{
+		{"0.0.0.0:8443", "http://example.com:8080/v1/contact/42?fields=name", "https://example.com:8443/v1/contact/42?fields=name"},
+		{":443", "http://example.com:8080/ping", "https://example.com/ping"},
+		{"0.0.0.0:8443", "http://example.com/", "https://example.com:8443/"},
+		{"[::1]:8443", "http://[::1]:8080/ping", "https://[::1]:8443/ping"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		NewHttpsRedirectHandler(c.httpsAddress).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, c.target, nil))
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("Target %v. Unexpected status: %v", c.target, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != c.expected {
+			t.Errorf("Target %v. Expected: %v. Got: %v", c.target, c.expected, location)
+		}
+	}
+}
+
+//freeAddress is an address of a port, that was free a moment ago
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	return listener.Addr().String()
+}
+
+func newTestTlsClient(ca *testCa, certs ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      ca.Pool(),
+				Certificates: certs,
+			},
+			ForceAttemptHTTP2: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: time.Second,
+	}
+}
+
+func get(client *http.Client, url string) (*http.Response, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+func TestNewTlsService(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	t.Run("HTTP/2 is served and plain http is redirected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		certFile, keyFile := ca.Issue(t, "server")
+		address := freeAddress(t)
+		opts := TlsOptions{CertFile: certFile, KeyFile: keyFile, RedirectAddress: freeAddress(t)}
+		s, err := NewTlsService(address, ok, opts, mock_logs.NewMockLogger(ctrl))
+		mocks.CmpError(t, err, nil)
+		defer func() {
+			_ = s.Stop()
+		}()
+
+		client := newTestTlsClient(ca)
+		resp, err := get(client, "https://"+address+"/ping")
+		mocks.CmpError(t, err, nil)
+		if resp.StatusCode != http.StatusNoContent || resp.ProtoMajor != 2 {
+			t.Errorf("Unexpected response: %v %v", resp.Proto, resp.StatusCode)
+		}
+		resp, err = get(client, "http://"+opts.RedirectAddress+"/ping?a=b")
+		mocks.CmpError(t, err, nil)
+		if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != "https://"+address+"/ping?a=b" {
+			t.Errorf("Unexpected redirect: %v %v", resp.StatusCode, resp.Header.Get("Location"))
+		}
+	})
+
+	t.Run("client certificate is required", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		certFile, keyFile := ca.Issue(t, "server")
+		address := freeAddress(t)
+		opts := TlsOptions{CertFile: certFile, KeyFile: keyFile, ClientCaFile: ca.Path("ca.pem"), RequireClientCert: true}
+		s, err := NewTlsService(address, ok, opts, mock_logs.NewMockLogger(ctrl))
+		mocks.CmpError(t, err, nil)
+		defer func() {
+			_ = s.Stop()
+		}()
+
+		_, err = get(newTestTlsClient(ca), "https://"+address+"/ping")
+		if err == nil {
+			t.Errorf("Expected error without client certificate.")
+		}
+		clientCert, clientKey := ca.Issue(t, "client")
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		mocks.CmpError(t, err, nil)
+		resp, err := get(newTestTlsClient(ca, cert), "https://"+address+"/ping")
+		mocks.CmpError(t, err, nil)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("Unexpected status: %v", resp.StatusCode)
+		}
+	})
+
+	t.Run("busy redirect port releases https port", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ca, cleanup := newTestCa(t)
+		defer cleanup()
+
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		mocks.CmpError(t, err, nil)
+		defer func() {
+			_ = busy.Close()
+		}()
+		certFile, keyFile := ca.Issue(t, "server")
+		address := freeAddress(t)
+		opts := TlsOptions{CertFile: certFile, KeyFile: keyFile, RedirectAddress: busy.Addr().String()}
+		_, err = NewTlsService(address, ok, opts, mock_logs.NewMockLogger(ctrl))
+		if err == nil {
+			t.Fatalf("Expected error.")
+		}
+		listener, err := net.Listen("tcp", address)
+		mocks.CmpError(t, err, nil)
+		_ = listener.Close()
+	})
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+	"time"
+
+	"github.com/coldze/test/logs"
+)
+
+//fileVersion changes, when file is modified, replaced or removed
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func (v fileVersion) equal(other fileVersion) bool {
+	return v.exists == other.exists && v.size == other.size && v.modTime.Equal(other.modTime)
+}
+
+func statFile(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		exists:  true,
+	}
+}
+
+//WatchFile polls file every interval and notifies, when it changes. Polling doesn't need OS-specific notifications and
+//works with files, mounted to containers, where symlinks are replaced instead of files being written.
+func WatchFile(path string, interval time.Duration, stop <-chan struct{}, logger logs.Logger) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := statFile(path)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			current := statFile(path)
+			if current.equal(last) {
+				continue
+			}
+			last = current
+			logger.Infof("File '%v' changed.", path)
+			Notify(changed)
+		}
+	}()
+	return changed
+}
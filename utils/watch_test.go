@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/coldze/test/mocks/mock_logs"
+)
+
+const (
+	test_watch_interval = 5 * time.Millisecond
+	test_watch_wait     = time.Second
+)
+
+type watchFixture struct {
+	Path   string
+	Stop   chan struct{}
+	Logger *mock_logs.MockLogger
+}
+
+func newWatchFixture(t *testing.T, ctrl *gomock.Controller) (*watchFixture, func()) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	f := &watchFixture{
+		Path:   filepath.Join(dir, "config.json"),
+		Stop:   make(chan struct{}),
+		Logger: mock_logs.NewMockLogger(ctrl),
+	}
+	f.write(t, "{}")
+	//watcher might outlive test and see removal of file, so logger never fails. Changes are checked by notifications.
+	f.Logger.EXPECT().Infof(gomock.Any(), f.Path).AnyTimes()
+	return f, func() {
+		close(f.Stop)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func (f *watchFixture) write(t *testing.T, data string) {
+	err := ioutil.WriteFile(f.Path, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func waitForChange(t *testing.T, changed <-chan struct{}) {
+	select {
+	case <-changed:
+	case <-time.After(test_watch_wait):
+		t.Errorf("Change is not notified.")
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+
+	t.Run("changed file is notified", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f, cleanup := newWatchFixture(t, ctrl)
+		defer cleanup()
+
+		changed := WatchFile(f.Path, test_watch_interval, f.Stop, f.Logger)
+		time.Sleep(2 * test_watch_interval)
+		f.write(t, `{"log": {"level": "error"}}`)
+		waitForChange(t, changed)
+	})
+
+	t.Run("removed file is notified", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f, cleanup := newWatchFixture(t, ctrl)
+		defer cleanup()
+
+		changed := WatchFile(f.Path, test_watch_interval, f.Stop, f.Logger)
+		time.Sleep(2 * test_watch_interval)
+		err := os.Remove(f.Path)
+		if err != nil {
+			t.Fatalf("Failed to remove file: %v", err)
+		}
+		waitForChange(t, changed)
+	})
+
+	t.Run("file, that is not changed, is not notified", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		f, cleanup := newWatchFixture(t, ctrl)
+		defer cleanup()
+
+		changed := WatchFile(f.Path, test_watch_interval, f.Stop, f.Logger)
+		select {
+		case <-changed:
+			t.Errorf("Unexpected notification.")
+		case <-time.After(10 * test_watch_interval):
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/coldze/test/logic"
+)
+
+//webhookCfg lets external API notify service about changed values. Webhook is enabled only if secret is set.
+type webhookCfg struct {
+	Secret           string `json:"secret"`
+	SecretFile       string `json:"secret_file"`
+	SignatureHeader  string `json:"signature_header"`
+	TimestampHeader  string `json:"timestamp_header"`
+	ToleranceSeconds int    `json:"tolerance_seconds"`
+}
+
+func (w *webhookCfg) IsEnabled() bool {
+	return len(w.Secret) > 0
+}
+
+//by default signature and timestamp are taken from X-Webhook-Signature and X-Webhook-Timestamp, tolerance is 5 minutes
+func (w *webhookCfg) GetSignatureOptions() logic.SignatureOptions {
+	return logic.SignatureOptions{
+		Secret:          []byte(w.Secret),
+		SignatureHeader: w.SignatureHeader,
+		TimestampHeader: w.TimestampHeader,
+		Tolerance:       time.Duration(w.ToleranceSeconds) * time.Second,
+	}
+}
+
+//webhooks are small, so default limit is used
+func (w *webhookCfg) newBodyExtractor() logic.RequestDataExtractor {
+	return logic.NewSignatureExtractor(w.GetSignatureOptions(), logic.NewBodyLimitExtractor(default_max_body_bytes, logic.GetRequestBodyData))
+}
+
+func (w *webhookCfg) validate() error {
+	if w.ToleranceSeconds < 0 {
+		return errors.New("webhook.tolerance_seconds must not be negative")
+	}
+	return nil
+}